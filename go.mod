@@ -7,6 +7,7 @@ require (
 	github.com/MeidoPromotionAssociation/MeidoSerialization v1.7.0
 	github.com/emmansun/base64 v0.10.0
 	github.com/wailsapp/wails/v2 v2.13.0
+	golang.org/x/text v0.40.0
 )
 
 require (
@@ -37,5 +38,4 @@ require (
 	golang.org/x/crypto v0.54.0 // indirect
 	golang.org/x/net v0.57.0 // indirect
 	golang.org/x/sys v0.47.0 // indirect
-	golang.org/x/text v0.40.0 // indirect
 )
@@ -0,0 +1,609 @@
+package COM3D2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// BVH 导出的默认帧率
+const defaultBvhFPS = 30
+
+// BvhExportOptions 导出 BVH 的选项
+type BvhExportOptions struct {
+	FPS       float32 `json:"FPS"`       // 采样帧率，0 时使用默认值 30
+	UnitScale float32 `json:"UnitScale"` // 位置的缩放倍数，0 时为 1，需要以厘米为单位时可设为 100
+	RootBone  string  `json:"RootBone"`  // 导出该骨骼及其所有子骨骼，为空时使用模型的 RootBoneName
+}
+
+// BvhExportResult 导出 BVH 的结果
+type BvhExportResult struct {
+	FrameCount     int      `json:"FrameCount"`     // 导出的帧数
+	JointCount     int      `json:"JointCount"`     // 导出的关节数
+	UnmatchedBones []string `json:"UnmatchedBones"` // .anm 中有曲线、但不在导出骨架中的骨骼
+}
+
+// BvhImportOptions 导入 BVH 的选项
+type BvhImportOptions struct {
+	BoneMapping  map[string]string `json:"BoneMapping"`  // BVH 关节名到 COM3D2 骨骼名的映射，值为空字符串表示忽略该关节；没有列出的关节按同名匹配，下划线视为空格
+	UnitScale    float32           `json:"UnitScale"`    // BVH 中的位置会除以该值，0 时为 1，BVH 以厘米为单位时可设为 100
+	Retarget     bool              `json:"Retarget"`     // 为 true 时假定 BVH 的零姿态与模型的静止姿态一致，按世界空间旋转重定向；为 false 时直接使用 BVH 的局部旋转，适用于由 ExportAnmToBvh 导出的文件
+	AllPositions bool              `json:"AllPositions"` // 为 true 时导入所有关节的位置通道，否则只导入根关节的位置
+}
+
+// BvhImportResult 导入 BVH 的结果
+type BvhImportResult struct {
+	FrameCount     int      `json:"FrameCount"`     // 导入的帧数
+	ImportedBones  []string `json:"ImportedBones"`  // 写入 .anm 的骨骼
+	UnmappedJoints []string `json:"UnmappedJoints"` // 没有对应骨骼、被忽略的 BVH 关节
+}
+
+// bvhJoint BVH 中的一个关节
+type bvhJoint struct {
+	name          string
+	parent        int
+	offset        COM3D2.Vector3
+	channels      []string // 小写的通道名，例如 xposition、zrotation
+	channelOffset int      // 该关节的第一个通道在每帧数据中的位置
+}
+
+// bvhMotion 解析后的 BVH 文件
+type bvhMotion struct {
+	joints    []bvhJoint
+	frameTime float32
+	frames    [][]float32
+}
+
+// ExportAnmToBvh 以 modelPath 的骨架为层级，将 .anm 按固定帧率采样后导出为 BVH
+// .anm 中没有曲线的骨骼使用模型的静止姿态，Unity 的左手坐标系会转换为 BVH 常用的右手坐标系（X 轴取反），旋转通道顺序为 ZXY
+// 关节名中的空白字符会被替换为下划线
+func (m *AnmService) ExportAnmToBvh(inputPath string, modelPath string, outputPath string, options BvhExportOptions) (result BvhExportResult, err error) {
+	anmData, err := m.ReadAnmFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read anm file: %w", err)
+	}
+	modelData, err := (&ModelService{}).ReadModelFile(modelPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return result, fmt.Errorf("unable to create bvh file: %w", err)
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	result, err = writeBvh(bw, anmData, modelData, options)
+	if err != nil {
+		return result, err
+	}
+	if err := bw.Flush(); err != nil {
+		return result, fmt.Errorf("an error occurred while flush bufio: %w", err)
+	}
+	return result, nil
+}
+
+// ImportBvhToAnm 读取 BVH，按骨骼名映射转换为 .anm 并写入 outputPath
+// modelPath 提供目标骨架，用于确定 BonePath 和重定向时的静止姿态
+func (m *AnmService) ImportBvhToAnm(inputPath string, modelPath string, outputPath string, options BvhImportOptions) (result BvhImportResult, err error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("cannot open bvh file: %w", err)
+	}
+	defer f.Close()
+
+	motion, err := parseBvh(bufio.NewReader(f))
+	if err != nil {
+		return result, fmt.Errorf("parsing the bvh file failed: %w", err)
+	}
+	modelData, err := (&ModelService{}).ReadModelFile(modelPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	anmData, result, err := bvhToAnm(motion, modelData, options)
+	if err != nil {
+		return result, err
+	}
+	if err := m.WriteAnmFile(outputPath, anmData); err != nil {
+		return result, fmt.Errorf("failed to write anm file: %w", err)
+	}
+	return result, nil
+}
+
+// writeBvh 采样 anmData 并以 BVH 格式写入 w
+func writeBvh(w io.Writer, anmData *COM3D2.Anm, modelData *COM3D2.Model, options BvhExportOptions) (result BvhExportResult, err error) {
+	fps := options.FPS
+	if fps <= 0 {
+		fps = defaultBvhFPS
+	}
+	scale := options.UnitScale
+	if scale == 0 {
+		scale = 1
+	}
+	rootName := options.RootBone
+	if rootName == "" {
+		rootName = modelData.RootBoneName
+	}
+	root := findBone(modelData, rootName)
+	if root < 0 {
+		return result, fmt.Errorf("root bone %q not found in model", rootName)
+	}
+
+	children := make([][]int, len(modelData.Bones))
+	for i, bone := range modelData.Bones {
+		if parent := int(bone.ParentIndex); parent >= 0 && parent < len(modelData.Bones) && parent != i {
+			children[parent] = append(children[parent], i)
+		}
+	}
+	tracks := anmBoneTracks(anmData)
+
+	// 深度优先确定关节顺序，根关节和有位置曲线的关节带位置通道
+	var order []int
+	hasPosition := make(map[int]bool)
+	visited := make([]bool, len(modelData.Bones))
+	var collect func(i int)
+	collect = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		order = append(order, i)
+		track := tracks[modelData.Bones[i].Name]
+		hasPosition[i] = i == root || (track != nil && track.hasPosition())
+		for _, child := range children[i] {
+			collect(child)
+		}
+	}
+	collect(root)
+
+	result.JointCount = len(order)
+	result.UnmatchedBones = []string{}
+	for name := range tracks {
+		if index := findBone(modelData, name); index < 0 || !visited[index] {
+			result.UnmatchedBones = append(result.UnmatchedBones, name)
+		}
+	}
+	slices.Sort(result.UnmatchedBones)
+
+	// HIERARCHY
+	var sb strings.Builder
+	sb.WriteString("HIERARCHY\n")
+	written := make([]bool, len(modelData.Bones))
+	var writeJoint func(i int, depth int)
+	writeJoint = func(i int, depth int) {
+		written[i] = true
+		indent := strings.Repeat("\t", depth)
+		bone := modelData.Bones[i]
+		keyword := "JOINT"
+		if i == root {
+			keyword = "ROOT"
+		}
+		offset := vec3Scale(unityToBvhPosition(bone.Position), scale)
+		fmt.Fprintf(&sb, "%s%s %s\n%s{\n", indent, keyword, bvhJointName(bone.Name), indent)
+		fmt.Fprintf(&sb, "%s\tOFFSET %s %s %s\n", indent, formatBvhFloat(offset.X), formatBvhFloat(offset.Y), formatBvhFloat(offset.Z))
+		if hasPosition[i] {
+			fmt.Fprintf(&sb, "%s\tCHANNELS 6 Xposition Yposition Zposition Zrotation Xrotation Yrotation\n", indent)
+		} else {
+			fmt.Fprintf(&sb, "%s\tCHANNELS 3 Zrotation Xrotation Yrotation\n", indent)
+		}
+		childCount := 0
+		for _, child := range children[i] {
+			if visited[child] && !written[child] {
+				writeJoint(child, depth+1)
+				childCount++
+			}
+		}
+		if childCount == 0 {
+			fmt.Fprintf(&sb, "%s\tEnd Site\n%s\t{\n%s\t\tOFFSET 0 0 0\n%s\t}\n", indent, indent, indent, indent)
+		}
+		fmt.Fprintf(&sb, "%s}\n", indent)
+	}
+	writeJoint(root, 0)
+
+	// MOTION
+	duration := anmDuration(anmData)
+	result.FrameCount = int(math.Floor(float64(duration*fps)+1e-4)) + 1
+	fmt.Fprintf(&sb, "MOTION\nFrames: %d\nFrame Time: %s\n", result.FrameCount, formatBvhFloat(1/fps))
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return result, fmt.Errorf("failed to write bvh file: %w", err)
+	}
+
+	previous := make(map[int][3]float64, len(order))
+	for frame := 0; frame < result.FrameCount; frame++ {
+		sb.Reset()
+		positions, rotations := sampleLocalPose(tracks, modelData.Bones, float32(frame)/fps)
+		for _, i := range order {
+			position, rotation := positions[i], rotations[i]
+			if hasPosition[i] {
+				p := vec3Scale(unityToBvhPosition(position), scale)
+				fmt.Fprintf(&sb, "%s %s %s ", formatBvhFloat(p.X), formatBvhFloat(p.Y), formatBvhFloat(p.Z))
+			}
+			euler := quatToEulerZXY(unityToBvhRotation(rotation))
+			if last, ok := previous[i]; ok {
+				for a := range euler {
+					euler[a] = unwrapDegrees(euler[a], last[a])
+				}
+			}
+			previous[i] = euler
+			fmt.Fprintf(&sb, "%s %s %s ", formatBvhFloat(float32(euler[0])), formatBvhFloat(float32(euler[1])), formatBvhFloat(float32(euler[2])))
+		}
+		line := strings.TrimRight(sb.String(), " ") + "\n"
+		if _, err := io.WriteString(w, line); err != nil {
+			return result, fmt.Errorf("failed to write bvh file: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// bvhToAnm 将 BVH 动作转换为以 modelData 为骨架的 Anm
+func bvhToAnm(motion *bvhMotion, modelData *COM3D2.Model, options BvhImportOptions) (*COM3D2.Anm, BvhImportResult, error) {
+	result := BvhImportResult{ImportedBones: []string{}, UnmappedJoints: []string{}}
+	scale := options.UnitScale
+	if scale == 0 {
+		scale = 1
+	}
+
+	// BVH 关节到模型骨骼的映射，同一骨骼只取第一个映射到它的关节
+	jointToBone := make([]int, len(motion.joints))
+	boneToJoint := make(map[int]int)
+	for j, joint := range motion.joints {
+		jointToBone[j] = -1
+		target, ok := options.BoneMapping[joint.name]
+		if !ok {
+			target = joint.name
+			if findBone(modelData, target) < 0 {
+				target = strings.ReplaceAll(target, "_", " ")
+			}
+		}
+		index := -1
+		if target != "" {
+			index = findBone(modelData, target)
+		}
+		if _, used := boneToJoint[index]; index < 0 || used {
+			result.UnmappedJoints = append(result.UnmappedJoints, joint.name)
+			continue
+		}
+		jointToBone[j] = index
+		boneToJoint[index] = j
+	}
+	if len(boneToJoint) == 0 {
+		return nil, result, fmt.Errorf("no bvh joint matches a bone of the model")
+	}
+
+	restWorld := boneWorldRotations(modelData.Bones)
+	frameCount := len(motion.frames)
+	times := make([]float32, frameCount)
+	positions := make(map[int][]COM3D2.Vector3)
+	rotations := make(map[int][]COM3D2.Quaternion)
+	for index, j := range boneToJoint {
+		rotations[index] = make([]COM3D2.Quaternion, frameCount)
+		if len(bvhPositionChannels(motion.joints[j])) > 0 && (options.AllPositions || motion.joints[j].parent < 0) {
+			positions[index] = make([]COM3D2.Vector3, frameCount)
+		}
+	}
+
+	jointLocal := make([]COM3D2.Quaternion, len(motion.joints))
+	jointWorld := make([]COM3D2.Quaternion, len(motion.joints))
+	for frame, values := range motion.frames {
+		times[frame] = float32(frame) * motion.frameTime
+
+		// BVH 关节的局部与世界旋转（已转换到 Unity 坐标系），关节总是排在其子关节之前
+		for j, joint := range motion.joints {
+			jointLocal[j] = bvhToUnityRotation(bvhJointRotation(joint, values))
+			if joint.parent >= 0 {
+				jointWorld[j] = quatNormalize(quatMul(jointWorld[joint.parent], jointLocal[j]))
+			} else {
+				jointWorld[j] = jointLocal[j]
+			}
+		}
+
+		for index, j := range boneToJoint {
+			if p := positions[index]; p != nil {
+				position := bvhJointPosition(motion.joints[j], values)
+				p[frame] = vec3Scale(bvhToUnityPosition(position), 1/scale)
+			}
+		}
+
+		if !options.Retarget {
+			for index, j := range boneToJoint {
+				rotations[index][frame] = jointLocal[j]
+			}
+			continue
+		}
+
+		// 重定向：目标骨骼的世界旋转 = BVH 关节的世界旋转 * 目标骨骼的静止世界旋转，没有映射的骨骼保持静止局部旋转
+		targetWorld := make(map[int]COM3D2.Quaternion, len(boneToJoint))
+		for index, j := range boneToJoint {
+			targetWorld[index] = quatMul(jointWorld[j], restWorld[index])
+		}
+		for index, local := range retargetLocalRotations(modelData.Bones, targetWorld) {
+			rotations[index][frame] = local
+		}
+	}
+
+	anmData := &COM3D2.Anm{
+		Signature: "CM3D2_ANIM",
+		Version:   defaultAnmVersion,
+	}
+	for j := range motion.joints {
+		index := jointToBone[j]
+		if index < 0 {
+			continue
+		}
+		boneCurve := sampledBoneCurve(bonePath(modelData.Bones, index), times, positions[index], rotations[index])
+		anmData.BoneCurves = append(anmData.BoneCurves, boneCurve)
+		result.ImportedBones = append(result.ImportedBones, modelData.Bones[index].Name)
+	}
+	result.FrameCount = frameCount
+	return anmData, result, nil
+}
+
+// parseBvh 解析 BVH 文件的 HIERARCHY 和 MOTION 部分
+func parseBvh(r io.Reader) (*bvhMotion, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	motion := &bvhMotion{}
+
+	// HIERARCHY：stack 中为当前所在的关节，End Site 记为 -1
+	var stack []int
+	pending := -1
+	channelCount := 0
+	inMotion := false
+	for !inMotion && scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "HIERARCHY":
+		case "ROOT", "JOINT":
+			name := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line[len(fields[0]):]), "{"))
+			parent := -1
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+				if parent < 0 {
+					return nil, fmt.Errorf("joint %q is inside an End Site", name)
+				}
+			}
+			motion.joints = append(motion.joints, bvhJoint{name: name, parent: parent})
+			pending = len(motion.joints) - 1
+			if strings.HasSuffix(line, "{") {
+				stack = append(stack, pending)
+			}
+		case "END":
+			pending = -1
+			if strings.HasSuffix(line, "{") {
+				stack = append(stack, pending)
+			}
+		case "{":
+			stack = append(stack, pending)
+		case "}":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unbalanced braces in hierarchy")
+			}
+			stack = stack[:len(stack)-1]
+		case "OFFSET":
+			if len(stack) == 0 || stack[len(stack)-1] < 0 {
+				continue
+			}
+			values, err := parseBvhFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OFFSET: %w", err)
+			}
+			motion.joints[stack[len(stack)-1]].offset = COM3D2.Vector3{X: values[0], Y: values[1], Z: values[2]}
+		case "CHANNELS":
+			if len(stack) == 0 || stack[len(stack)-1] < 0 || len(fields) < 2 {
+				return nil, fmt.Errorf("unexpected CHANNELS")
+			}
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 0 || len(fields) != n+2 {
+				return nil, fmt.Errorf("invalid CHANNELS line %q", line)
+			}
+			joint := &motion.joints[stack[len(stack)-1]]
+			joint.channelOffset = channelCount
+			joint.channels = make([]string, n)
+			for i, channel := range fields[2:] {
+				joint.channels[i] = strings.ToLower(channel)
+			}
+			channelCount += n
+		case "MOTION":
+			inMotion = true
+		default:
+			return nil, fmt.Errorf("unexpected line in hierarchy: %q", line)
+		}
+	}
+	if !inMotion {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("MOTION section not found")
+	}
+	if len(motion.joints) == 0 {
+		return nil, fmt.Errorf("no joints in hierarchy")
+	}
+
+	// MOTION：Frames 和 Frame Time 之后为逐帧数据，允许一帧跨多行
+	frameCount := -1
+	var values []float32
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lower := strings.ToLower(line)
+		switch {
+		case strings.HasPrefix(lower, "frames:"):
+			n, err := strconv.Atoi(strings.TrimSpace(line[len("frames:"):]))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid frame count %q", line)
+			}
+			frameCount = n
+		case strings.HasPrefix(lower, "frame time:"):
+			t, err := strconv.ParseFloat(strings.TrimSpace(line[len("frame time:"):]), 32)
+			if err != nil || t <= 0 {
+				return nil, fmt.Errorf("invalid frame time %q", line)
+			}
+			motion.frameTime = float32(t)
+		default:
+			fields := strings.Fields(line)
+			parsed, err := parseBvhFloats(fields, len(fields))
+			if err != nil {
+				return nil, fmt.Errorf("invalid frame data: %w", err)
+			}
+			values = append(values, parsed...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if frameCount < 0 || motion.frameTime <= 0 {
+		return nil, fmt.Errorf("missing Frames or Frame Time")
+	}
+	if channelCount == 0 {
+		return nil, fmt.Errorf("no channels in hierarchy")
+	}
+	if len(values) < frameCount*channelCount {
+		return nil, fmt.Errorf("expected %d frames of %d channels, got %d values", frameCount, channelCount, len(values))
+	}
+	motion.frames = make([][]float32, frameCount)
+	for i := range motion.frames {
+		motion.frames[i] = values[i*channelCount : (i+1)*channelCount]
+	}
+	return motion, nil
+}
+
+// parseBvhFloats 解析 n 个浮点数
+func parseBvhFloats(fields []string, n int) ([]float32, error) {
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d numbers, got %d", n, len(fields))
+	}
+	values := make([]float32, n)
+	for i := 0; i < n; i++ {
+		v, err := strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = float32(v)
+	}
+	return values, nil
+}
+
+// bvhPositionChannels 返回关节的位置通道名
+func bvhPositionChannels(joint bvhJoint) []string {
+	var channels []string
+	for _, channel := range joint.channels {
+		if strings.HasSuffix(channel, "position") {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// bvhJointPosition 关节在某一帧的位置（BVH 坐标），没有位置通道的分量使用 OFFSET
+func bvhJointPosition(joint bvhJoint, values []float32) COM3D2.Vector3 {
+	position := joint.offset
+	for i, channel := range joint.channels {
+		value := values[joint.channelOffset+i]
+		switch channel {
+		case "xposition":
+			position.X = value
+		case "yposition":
+			position.Y = value
+		case "zposition":
+			position.Z = value
+		}
+	}
+	return position
+}
+
+// bvhJointRotation 关节在某一帧的局部旋转（BVH 坐标），按通道的书写顺序依次相乘
+func bvhJointRotation(joint bvhJoint, values []float32) COM3D2.Quaternion {
+	rotation := quatIdentity()
+	for i, channel := range joint.channels {
+		var axis COM3D2.Vector3
+		switch channel {
+		case "xrotation":
+			axis.X = 1
+		case "yrotation":
+			axis.Y = 1
+		case "zrotation":
+			axis.Z = 1
+		default:
+			continue
+		}
+		angle := float64(values[joint.channelOffset+i]) * math.Pi / 180
+		rotation = quatMul(rotation, quatFromAxisAngle(axis, angle))
+	}
+	return quatNormalize(rotation)
+}
+
+// quatToEulerZXY 将四元数分解为 q = Rz * Rx * Ry，返回 Z、X、Y 的角度（度），与 BVH 通道 Zrotation Xrotation Yrotation 对应
+func quatToEulerZXY(q COM3D2.Quaternion) [3]float64 {
+	x, y, z, w := float64(q.X), float64(q.Y), float64(q.Z), float64(q.W)
+	m00 := 1 - 2*(y*y+z*z)
+	m01 := 2 * (x*y - z*w)
+	m10 := 2 * (x*y + z*w)
+	m11 := 1 - 2*(x*x+z*z)
+	m20 := 2 * (x*z - y*w)
+	m21 := 2 * (y*z + x*w)
+	m22 := 1 - 2*(x*x+y*y)
+
+	toDeg := 180 / math.Pi
+	sx := math.Max(-1, math.Min(1, m21))
+	ax := math.Asin(sx)
+	if math.Abs(sx) > 0.9999 {
+		// 万向节锁：Y 取 0，Z 吸收剩余的旋转
+		return [3]float64{math.Atan2(m10, m00) * toDeg, ax * toDeg, 0}
+	}
+	return [3]float64{math.Atan2(-m01, m11) * toDeg, ax * toDeg, math.Atan2(-m20, m22) * toDeg}
+}
+
+// unwrapDegrees 给 angle 加减 360 的整数倍，使其最接近 previous，避免相邻帧间出现跳变
+func unwrapDegrees(angle float64, previous float64) float64 {
+	return angle + 360*math.Round((previous-angle)/360)
+}
+
+// unityToBvhPosition Unity 左手坐标系到右手坐标系，X 轴取反
+func unityToBvhPosition(v COM3D2.Vector3) COM3D2.Vector3 {
+	return COM3D2.Vector3{X: -v.X, Y: v.Y, Z: v.Z}
+}
+
+// bvhToUnityPosition 右手坐标系到 Unity 左手坐标系，X 轴取反
+func bvhToUnityPosition(v COM3D2.Vector3) COM3D2.Vector3 {
+	return unityToBvhPosition(v)
+}
+
+// unityToBvhRotation X 轴取反后旋转轴的 Y、Z 分量取反
+func unityToBvhRotation(q COM3D2.Quaternion) COM3D2.Quaternion {
+	return COM3D2.Quaternion{X: q.X, Y: -q.Y, Z: -q.Z, W: q.W}
+}
+
+// bvhToUnityRotation 与 unityToBvhRotation 相同，该变换是自身的逆
+func bvhToUnityRotation(q COM3D2.Quaternion) COM3D2.Quaternion {
+	return unityToBvhRotation(q)
+}
+
+// bvhJointName 将空白字符替换为下划线，大多数 BVH 解析器不支持带空格的关节名
+func bvhJointName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// formatBvhFloat 以最多 6 位小数输出浮点数
+func formatBvhFloat(f float32) string {
+	s := strconv.FormatFloat(float64(f), 'f', 6, 32)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,196 @@
+package COM3D2
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// .anm 中 PropertyCurve.PropertyIndex 的含义，局部旋转为四元数，局部位置为 Unity 坐标
+const (
+	anmPropertyRotationX = 0
+	anmPropertyRotationY = 1
+	anmPropertyRotationZ = 2
+	anmPropertyRotationW = 3
+	anmPropertyPositionX = 4
+	anmPropertyPositionY = 5
+	anmPropertyPositionZ = 6
+	anmPropertyCount     = 7
+)
+
+// anm 文件默认的签名版本
+const defaultAnmVersion = 1001
+
+// 时间差小于该值的两个关键帧视为同一时刻
+const keyframeTimeEpsilon = 1e-5
+
+// anmBoneName 返回 BonePath 的最后一段，即骨骼名
+func anmBoneName(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// anmDuration 返回动画的长度（秒），即所有关键帧中最大的时间
+func anmDuration(anmData *COM3D2.Anm) float32 {
+	var duration float32
+	for _, boneCurve := range anmData.BoneCurves {
+		for _, curve := range boneCurve.PropertyCurves {
+			if n := len(curve.Keyframes); n > 0 && curve.Keyframes[n-1].Time > duration {
+				duration = curve.Keyframes[n-1].Time
+			}
+		}
+	}
+	return duration
+}
+
+// evaluateKeyframeSlope 计算曲线在 time 时刻的斜率（每秒的变化量），超出关键帧范围或阶跃时为 0
+func evaluateKeyframeSlope(keys []COM3D2.Keyframe, time float32) float32 {
+	n := len(keys)
+	if n < 2 || time < keys[0].Time || time > keys[n-1].Time {
+		return 0
+	}
+	i := sort.Search(n, func(i int) bool { return keys[i].Time > time })
+	if i == n {
+		i = n - 1
+	}
+	k0, k1 := keys[i-1], keys[i]
+	dt := k1.Time - k0.Time
+	if dt <= 0 || isInfinite(k0.OutTangent) || isInfinite(k1.InTangent) {
+		return 0
+	}
+	s := (time - k0.Time) / dt
+	s2 := s * s
+	d00 := 6*s2 - 6*s
+	d10 := 3*s2 - 4*s + 1
+	d01 := -6*s2 + 6*s
+	d11 := 3*s2 - 2*s
+	return (d00*k0.Value+d01*k1.Value)/dt + d10*k0.OutTangent + d11*k1.InTangent
+}
+
+// keyframeSlopes 返回曲线在 time 时刻左右两侧的斜率，该时刻有关键帧时即为其入切线和出切线
+func keyframeSlopes(keys []COM3D2.Keyframe, time float32) (float32, float32) {
+	i := sort.Search(len(keys), func(i int) bool { return keys[i].Time > time-keyframeTimeEpsilon })
+	if i < len(keys) && keys[i].Time-time < keyframeTimeEpsilon {
+		return keys[i].InTangent, keys[i].OutTangent
+	}
+	slope := evaluateKeyframeSlope(keys, time)
+	return slope, slope
+}
+
+// insertKeyframe 在 time 处插入关键帧，曲线的形状保持不变，该时刻已有关键帧时不做修改
+// 三次曲线在子区间上仍是三次曲线，因此取原曲线在该点的值和斜率即可精确拆分
+func insertKeyframe(keys []COM3D2.Keyframe, time float32) []COM3D2.Keyframe {
+	i := sort.Search(len(keys), func(i int) bool { return keys[i].Time >= time })
+	if i < len(keys) && keys[i].Time-time < keyframeTimeEpsilon {
+		return keys
+	}
+	if i > 0 && time-keys[i-1].Time < keyframeTimeEpsilon {
+		return keys
+	}
+	key := COM3D2.Keyframe{Time: time, Value: evaluateKeyframes(keys, time)}
+	if i > 0 && i < len(keys) && (isInfinite(keys[i-1].OutTangent) || isInfinite(keys[i].InTangent)) {
+		key.InTangent, key.OutTangent = float32(math.Inf(1)), float32(math.Inf(1))
+	} else {
+		slope := evaluateKeyframeSlope(keys, time)
+		key.InTangent, key.OutTangent = slope, slope
+	}
+	return slices.Insert(keys, i, key)
+}
+
+// smoothTangents 按相邻关键帧的斜率设置切线（Catmull-Rom），首尾关键帧使用单侧斜率
+func smoothTangents(keys []COM3D2.Keyframe) {
+	n := len(keys)
+	if n < 2 {
+		for i := range keys {
+			keys[i].InTangent, keys[i].OutTangent = 0, 0
+		}
+		return
+	}
+	for i := range keys {
+		prev, next := max(i-1, 0), min(i+1, n-1)
+		dt := keys[next].Time - keys[prev].Time
+		var slope float32
+		if dt > 0 {
+			slope = (keys[next].Value - keys[prev].Value) / dt
+		}
+		keys[i].InTangent, keys[i].OutTangent = slope, slope
+	}
+}
+
+// sampledBoneCurve 将逐帧采样的局部位置和旋转转换为 BoneCurveData，旋转会保持在同一半球以避免插值翻转
+func sampledBoneCurve(path string, times []float32, positions []COM3D2.Vector3, rotations []COM3D2.Quaternion) COM3D2.BoneCurveData {
+	boneCurve := COM3D2.BoneCurveData{BonePath: path}
+	if len(rotations) > 0 {
+		for i := 1; i < len(rotations); i++ {
+			r, p := rotations[i], rotations[i-1]
+			if r.X*p.X+r.Y*p.Y+r.Z*p.Z+r.W*p.W < 0 {
+				rotations[i] = COM3D2.Quaternion{X: -r.X, Y: -r.Y, Z: -r.Z, W: -r.W}
+			}
+		}
+		components := []func(q COM3D2.Quaternion) float32{
+			func(q COM3D2.Quaternion) float32 { return q.X },
+			func(q COM3D2.Quaternion) float32 { return q.Y },
+			func(q COM3D2.Quaternion) float32 { return q.Z },
+			func(q COM3D2.Quaternion) float32 { return q.W },
+		}
+		for c, component := range components {
+			keys := make([]COM3D2.Keyframe, len(times))
+			for i, time := range times {
+				keys[i] = COM3D2.Keyframe{Time: time, Value: component(rotations[i])}
+			}
+			smoothTangents(keys)
+			boneCurve.PropertyCurves = append(boneCurve.PropertyCurves, COM3D2.PropertyCurve{PropertyIndex: anmPropertyRotationX + c, Keyframes: keys})
+		}
+	}
+	if len(positions) > 0 {
+		components := []func(v COM3D2.Vector3) float32{
+			func(v COM3D2.Vector3) float32 { return v.X },
+			func(v COM3D2.Vector3) float32 { return v.Y },
+			func(v COM3D2.Vector3) float32 { return v.Z },
+		}
+		for c, component := range components {
+			keys := make([]COM3D2.Keyframe, len(times))
+			for i, time := range times {
+				keys[i] = COM3D2.Keyframe{Time: time, Value: component(positions[i])}
+			}
+			smoothTangents(keys)
+			boneCurve.PropertyCurves = append(boneCurve.PropertyCurves, COM3D2.PropertyCurve{PropertyIndex: anmPropertyPositionX + c, Keyframes: keys})
+		}
+	}
+	return boneCurve
+}
+
+// bonePath 返回骨骼从根骨骼开始的 / 分隔路径，与 .anm 的 BonePath 格式一致
+func bonePath(bones []*COM3D2.Bone, index int) string {
+	var segments []string
+	for steps := 0; index >= 0 && index < len(bones) && steps <= len(bones); steps++ {
+		segments = append(segments, bones[index].Name)
+		parent := int(bones[index].ParentIndex)
+		if parent == index {
+			break
+		}
+		index = parent
+	}
+	for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+		segments[i], segments[j] = segments[j], segments[i]
+	}
+	return strings.Join(segments, "/")
+}
+
+// retargetLocalRotations 给定部分骨骼期望的世界旋转，计算这些骨骼的局部旋转，其余骨骼保持静止姿态的局部旋转
+func retargetLocalRotations(bones []*COM3D2.Bone, targetWorld map[int]COM3D2.Quaternion) map[int]COM3D2.Quaternion {
+	local := make(map[int]COM3D2.Quaternion, len(targetWorld))
+	composeWorldRotations(bones, func(i int, parentWorld COM3D2.Quaternion) COM3D2.Quaternion {
+		if target, ok := targetWorld[i]; ok {
+			local[i] = quatNormalize(quatMul(quatConjugate(parentWorld), target))
+			return target
+		}
+		return quatMul(parentWorld, bones[i].Rotation)
+	})
+	return local
+}
@@ -0,0 +1,299 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// AnmMirrorRule 左右骨骼名的对应规则，骨骼名中包含 Left 的部分替换为 Right，反之亦然
+// 标记按独立的词匹配：标记首尾的字母或数字不能与骨骼名中的其他字母或数字相连，例如 "_L" 匹配 "Hip_L" 和 "Mune_L_sub"，但不匹配 "hair_Long"
+type AnmMirrorRule struct {
+	Left  string `json:"Left"`  // 左侧骨骼名中的标记，例如 " L "
+	Right string `json:"Right"` // 右侧骨骼名中的标记，例如 " R "
+}
+
+// AnmMirrorOptions 镜像动画的选项
+type AnmMirrorOptions struct {
+	Rules     []AnmMirrorRule `json:"Rules"`     // 左右命名规则，按顺序使用第一条匹配的规则，为空时使用 " L "/" R " 和 "_L"/"_R"
+	ModelPath string          `json:"ModelPath"` // 可选的 .model 骨架，提供时按每个骨骼及其对侧骨骼的静止姿态确定需要取反的分量
+	Axis      string          `json:"Axis"`      // 没有骨架时，骨骼局部空间中与对称面垂直的轴，X、Y 或 Z，为空时为 Z（与 Biped 骨骼一致），根骨骼的父空间总是使用 X
+}
+
+// AnmMirrorResult 镜像动画的结果
+type AnmMirrorResult struct {
+	SwappedBones []string `json:"SwappedBones"` // 按命名规则交换了左右的骨骼路径（交换前）
+	Warnings     []string `json:"Warnings"`     // 镜像过程中的警告，例如骨架不对称的骨骼
+}
+
+// 默认的左右命名规则
+var defaultAnmMirrorRules = []AnmMirrorRule{
+	{Left: " L ", Right: " R "},
+	{Left: "_L", Right: "_R"},
+}
+
+// MirrorAnm 以角色的对称面（Unity 中的 X = 0 平面）镜像动画，左右骨骼的曲线按命名规则交换，并对位置和旋转的相应分量取反
+func (m *AnmService) MirrorAnm(inputPath string, outputPath string, options AnmMirrorOptions) (result AnmMirrorResult, err error) {
+	anmData, err := m.ReadAnmFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read anm file: %w", err)
+	}
+	var modelData *COM3D2.Model
+	if options.ModelPath != "" {
+		modelData, err = (&ModelService{}).ReadModelFile(options.ModelPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to read model file: %w", err)
+		}
+	}
+	result, err = mirrorAnm(anmData, modelData, options)
+	if err != nil {
+		return result, err
+	}
+	if err := m.WriteAnmFile(outputPath, anmData); err != nil {
+		return result, fmt.Errorf("failed to write anm file: %w", err)
+	}
+	return result, nil
+}
+
+// mirrorAnm 镜像 anmData，modelData 可以为 nil
+//
+// 设世界空间的镜像为 S，骨骼的父空间静止旋转为 P，对侧骨骼的父空间为 P'，则局部位置 p' = A p，其中 A = P^-1 S P'
+// 骨骼自身的静止旋转为 R、对侧为 R' 时，局部旋转矩阵 Q' = A Q B，其中 B = R^-1 S R'
+// 对称的骨架中 A、B 都是对角矩阵，因此每个分量只需取反或与另一分量交换，关键帧的时间和切线形状保持不变
+func mirrorAnm(anmData *COM3D2.Anm, modelData *COM3D2.Model, options AnmMirrorOptions) (result AnmMirrorResult, err error) {
+	rules := options.Rules
+	if len(rules) == 0 {
+		rules = defaultAnmMirrorRules
+	}
+	for _, rule := range rules {
+		if rule.Left == "" || rule.Right == "" || rule.Left == rule.Right {
+			return result, fmt.Errorf("invalid mirror rule %q/%q", rule.Left, rule.Right)
+		}
+	}
+	axis := 2
+	switch strings.ToUpper(options.Axis) {
+	case "X":
+		axis = 0
+	case "Y":
+		axis = 1
+	case "", "Z":
+	default:
+		return result, fmt.Errorf("invalid mirror axis %q", options.Axis)
+	}
+	result.SwappedBones = []string{}
+	result.Warnings = []string{}
+	warn := func(format string, args ...any) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	var restWorld []COM3D2.Quaternion
+	if modelData != nil {
+		restWorld = boneWorldRotations(modelData.Bones)
+	}
+
+	for b := range anmData.BoneCurves {
+		boneCurve := &anmData.BoneCurves[b]
+		segments := strings.Split(boneCurve.BonePath, "/")
+		mirroredSegments := make([]string, len(segments))
+		for i, segment := range segments {
+			mirroredSegments[i] = mirrorBoneName(segment, rules)
+		}
+		mirroredPath := strings.Join(mirroredSegments, "/")
+		if mirroredPath != boneCurve.BonePath {
+			result.SwappedBones = append(result.SwappedBones, boneCurve.BonePath)
+		}
+
+		// 父空间和自身的镜像矩阵对角线
+		parentAxis := reflectionDiagonal(axis)
+		if len(segments) == 1 {
+			parentAxis = reflectionDiagonal(0)
+		}
+		ownAxis := reflectionDiagonal(axis)
+		if modelData != nil {
+			name, mirroredName := segments[len(segments)-1], mirroredSegments[len(segments)-1]
+			index, mirroredIndex := findBone(modelData, name), findBone(modelData, mirroredName)
+			switch {
+			case index < 0:
+				warn("bone %q not found in model, mirrored with axis %s", name, axisName(axis))
+			case mirroredIndex < 0:
+				warn("mirrored bone %q not found in model, mirrored with axis %s", mirroredName, axisName(axis))
+			default:
+				var symmetric bool
+				parent, mirroredParent := quatIdentity(), quatIdentity()
+				if p := int(modelData.Bones[index].ParentIndex); p >= 0 && p < len(modelData.Bones) && p != index {
+					parent = restWorld[p]
+				}
+				if p := int(modelData.Bones[mirroredIndex].ParentIndex); p >= 0 && p < len(modelData.Bones) && p != mirroredIndex {
+					mirroredParent = restWorld[p]
+				}
+				parentAxis, symmetric = mirrorDiagonal(parent, mirroredParent)
+				if !symmetric {
+					warn("parents of %q and %q are not mirror symmetric, the result may be inaccurate", name, mirroredName)
+				}
+				ownAxis, symmetric = mirrorDiagonal(restWorld[index], restWorld[mirroredIndex])
+				if !symmetric {
+					warn("%q and %q are not mirror symmetric, the result may be inaccurate", name, mirroredName)
+				}
+			}
+		}
+
+		boneCurve.BonePath = mirroredPath
+		boneCurve.PropertyCurves = mirrorPropertyCurves(boneCurve.PropertyCurves, parentAxis, ownAxis)
+	}
+
+	anmData.BustKeyLeft, anmData.BustKeyRight = anmData.BustKeyRight, anmData.BustKeyLeft
+	return result, nil
+}
+
+// mirrorBoneName 按第一条匹配的规则交换骨骼名中的左右标记，没有匹配时原样返回
+func mirrorBoneName(name string, rules []AnmMirrorRule) string {
+	for _, rule := range rules {
+		if i := findSideMarker(name, rule.Left); i >= 0 {
+			return name[:i] + rule.Right + name[i+len(rule.Left):]
+		}
+		if i := findSideMarker(name, rule.Right); i >= 0 {
+			return name[:i] + rule.Left + name[i+len(rule.Right):]
+		}
+	}
+	return name
+}
+
+// findSideMarker 返回 marker 在 name 中第一次作为独立的词出现的位置，没有时返回 -1
+func findSideMarker(name string, marker string) int {
+	for offset := 0; offset <= len(name)-len(marker); {
+		i := strings.Index(name[offset:], marker)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		end := i + len(marker)
+		startBounded := i == 0 || !isAlphanumeric(marker[0]) || !isAlphanumeric(name[i-1])
+		endBounded := end == len(name) || !isAlphanumeric(marker[len(marker)-1]) || !isAlphanumeric(name[end])
+		if startBounded && endBounded {
+			return i
+		}
+		offset = i + 1
+	}
+	return -1
+}
+
+// isAlphanumeric 判断 ASCII 字符是否为字母或数字
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// reflectionDiagonal 对某一轴取反的镜像矩阵的对角线
+func reflectionDiagonal(axis int) [3]float32 {
+	diagonal := [3]float32{1, 1, 1}
+	diagonal[axis] = -1
+	return diagonal
+}
+
+// axisName 轴的名称
+func axisName(axis int) string {
+	return [3]string{"X", "Y", "Z"}[axis]
+}
+
+// mirrorDiagonal 计算 R^-1 S R' 的对角线（S 为 Unity 中 X 轴的镜像），取每个元素的符号
+// 矩阵不接近对角矩阵时第二个返回值为 false
+func mirrorDiagonal(rotation COM3D2.Quaternion, mirrored COM3D2.Quaternion) ([3]float32, bool) {
+	var diagonal [3]float32
+	symmetric := true
+	for j := 0; j < 3; j++ {
+		var basis COM3D2.Vector3
+		switch j {
+		case 0:
+			basis.X = 1
+		case 1:
+			basis.Y = 1
+		case 2:
+			basis.Z = 1
+		}
+		v := quatRotate(mirrored, basis)
+		v.X = -v.X
+		column := quatRotate(quatConjugate(rotation), v)
+		value := [3]float32{column.X, column.Y, column.Z}[j]
+		diagonal[j] = 1
+		if value < 0 {
+			diagonal[j] = -1
+		}
+		if math.Abs(float64(value)) < 0.99 {
+			symmetric = false
+		}
+	}
+	return diagonal, symmetric
+}
+
+// mirrorPropertyCurves 按镜像矩阵变换曲线：位置 p' = A p，旋转 Q' = A Q B
+// A、B 的行列式为 -1，取反后为恒等或绕某轴 180 度的旋转 a、b，因此四元数 q' = a * q * b
+func mirrorPropertyCurves(curves []COM3D2.PropertyCurve, parentDiagonal [3]float32, ownDiagonal [3]float32) []COM3D2.PropertyCurve {
+	a, b := diagonalRotation(parentDiagonal), diagonalRotation(ownDiagonal)
+	transform := func(q COM3D2.Quaternion) COM3D2.Quaternion { return quatMul(quatMul(a, q), b) }
+
+	// 四元数分量的线性映射：每个输出分量等于某个输入分量乘以 ±1
+	var source [4]int
+	var sign [4]float32
+	basis := [4]COM3D2.Quaternion{{X: 1}, {Y: 1}, {Z: 1}, {W: 1}}
+	for j, e := range basis {
+		out := transform(e)
+		for c, value := range [4]float32{out.X, out.Y, out.Z, out.W} {
+			if math.Abs(float64(value)) > 0.5 {
+				source[c], sign[c] = j, value
+			}
+		}
+	}
+
+	byProperty := make(map[int]COM3D2.PropertyCurve, len(curves))
+	for _, curve := range curves {
+		byProperty[curve.PropertyIndex] = curve
+	}
+	mirrored := make([]COM3D2.PropertyCurve, 0, len(curves))
+	for _, curve := range curves {
+		switch {
+		case curve.PropertyIndex >= anmPropertyRotationX && curve.PropertyIndex <= anmPropertyRotationW:
+			c := curve.PropertyIndex - anmPropertyRotationX
+			from, ok := byProperty[anmPropertyRotationX+source[c]]
+			if !ok {
+				continue
+			}
+			mirrored = append(mirrored, COM3D2.PropertyCurve{PropertyIndex: curve.PropertyIndex, Keyframes: scaledKeyframes(from.Keyframes, sign[c])})
+		case curve.PropertyIndex >= anmPropertyPositionX && curve.PropertyIndex <= anmPropertyPositionZ:
+			c := curve.PropertyIndex - anmPropertyPositionX
+			mirrored = append(mirrored, COM3D2.PropertyCurve{PropertyIndex: curve.PropertyIndex, Keyframes: scaledKeyframes(curve.Keyframes, parentDiagonal[c])})
+		default:
+			mirrored = append(mirrored, curve)
+		}
+	}
+	return mirrored
+}
+
+// diagonalRotation 行列式为 -1 的对角矩阵取反后对应的四元数
+func diagonalRotation(diagonal [3]float32) COM3D2.Quaternion {
+	// 取反后对角线为 (1, 1, 1) 时为恒等，有两个 -1 时为绕剩下那个轴旋转 180 度
+	negated := [3]float32{-diagonal[0], -diagonal[1], -diagonal[2]}
+	switch {
+	case negated[0] > 0 && negated[1] < 0 && negated[2] < 0:
+		return COM3D2.Quaternion{X: 1}
+	case negated[1] > 0 && negated[0] < 0 && negated[2] < 0:
+		return COM3D2.Quaternion{Y: 1}
+	case negated[2] > 0 && negated[0] < 0 && negated[1] < 0:
+		return COM3D2.Quaternion{Z: 1}
+	}
+	return quatIdentity()
+}
+
+// scaledKeyframes 复制关键帧，值和切线乘以 sign
+func scaledKeyframes(keys []COM3D2.Keyframe, sign float32) []COM3D2.Keyframe {
+	scaled := make([]COM3D2.Keyframe, len(keys))
+	for i, key := range keys {
+		scaled[i] = COM3D2.Keyframe{
+			Time:       key.Time,
+			Value:      key.Value * sign,
+			InTangent:  key.InTangent * sign,
+			OutTangent: key.OutTangent * sign,
+		}
+	}
+	return scaled
+}
@@ -0,0 +1,379 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 采样动画姿态的限制
+const (
+	defaultAnmPoseFPS = 30    // 没有指定采样时刻时的默认帧率
+	maxAnmPoseSamples = 10000 // 按帧率采样时最多输出的姿态数，避免过长的动画一次返回过多数据
+)
+
+// AnmPoseOptions 采样动画姿态的选项
+type AnmPoseOptions struct {
+	Times     []float32 `json:"Times"`     // 采样时刻（秒），为空时按 FPS 从 0 采样到动画结尾
+	FPS       float32   `json:"FPS"`       // Times 为空时的采样帧率，0 时使用默认值 30
+	ModelPath string    `json:"ModelPath"` // 可选的 .model 骨架，提供时输出模型的所有骨骼，没有曲线的分量使用静止姿态，并计算世界空间变换
+	Bones     []string  `json:"Bones"`     // 只输出这些骨骼，为空时输出所有骨骼
+}
+
+// AnmBonePose 某一时刻单个骨骼的姿态
+type AnmBonePose struct {
+	Name          string            `json:"Name"`          // 骨骼名
+	Path          string            `json:"Path"`          // 骨骼路径，与 BonePath 格式一致
+	Position      COM3D2.Vector3    `json:"Position"`      // 局部位置
+	Rotation      COM3D2.Quaternion `json:"Rotation"`      // 局部旋转
+	HasPosition   bool              `json:"HasPosition"`   // 位置是否来自动画曲线，否则为静止姿态或 0
+	HasRotation   bool              `json:"HasRotation"`   // 旋转是否来自动画曲线，否则为静止姿态或单位四元数
+	WorldPosition COM3D2.Vector3    `json:"WorldPosition"` // 模型空间中的位置，只有提供 ModelPath 时有效
+	WorldRotation COM3D2.Quaternion `json:"WorldRotation"` // 模型空间中的旋转，只有提供 ModelPath 时有效
+}
+
+// AnmPose 某一时刻所有骨骼的姿态
+type AnmPose struct {
+	Time  float32       `json:"Time"`  // 采样时刻（秒）
+	Bones []AnmBonePose `json:"Bones"` // 骨骼姿态
+}
+
+// AnmPoseResult 采样动画姿态的结果
+type AnmPoseResult struct {
+	Duration       float32   `json:"Duration"`       // 动画长度（秒）
+	Poses          []AnmPose `json:"Poses"`          // 每个采样时刻的姿态
+	UnmatchedBones []string  `json:"UnmatchedBones"` // 提供 ModelPath 时，.anm 中有曲线但模型中没有的骨骼
+}
+
+// EvaluateAnmCurve 与游戏中的 AnimationCurve 一致，计算一条曲线在各个时刻的值
+func (m *AnmService) EvaluateAnmCurve(keyframes []COM3D2.Keyframe, times []float32) []float32 {
+	values := make([]float32, len(times))
+	for i, time := range times {
+		values[i] = evaluateKeyframes(keyframes, time)
+	}
+	return values
+}
+
+// SampleAnmPose 读取 .anm 或 .anm.json 文件，计算各个时刻每个骨骼的局部位置和旋转
+func (m *AnmService) SampleAnmPose(inputPath string, options AnmPoseOptions) (AnmPoseResult, error) {
+	anmData, err := m.ReadAnmFile(inputPath)
+	if err != nil {
+		return AnmPoseResult{}, fmt.Errorf("failed to read anm file: %w", err)
+	}
+	return m.SampleAnmDataPose(anmData, options)
+}
+
+// SampleAnmDataPose 与 SampleAnmPose 相同，但直接使用内存中的 Anm 数据，用于预览尚未保存的编辑
+func (m *AnmService) SampleAnmDataPose(anmData *COM3D2.Anm, options AnmPoseOptions) (result AnmPoseResult, err error) {
+	if anmData == nil {
+		return result, fmt.Errorf("anm data is nil")
+	}
+	var modelData *COM3D2.Model
+	if options.ModelPath != "" {
+		modelData, err = (&ModelService{}).ReadModelFile(options.ModelPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to read model file: %w", err)
+		}
+	}
+
+	result.Duration = anmDuration(anmData)
+	times, err := anmPoseTimes(result.Duration, options)
+	if err != nil {
+		return result, err
+	}
+	if modelData != nil {
+		result.Poses, result.UnmatchedBones = sampleModelPoses(anmData, modelData, times, options.Bones)
+	} else {
+		result.Poses = sampleAnmPoses(anmData, times, options.Bones)
+		result.UnmatchedBones = []string{}
+	}
+	return result, nil
+}
+
+// anmPoseTimes 确定采样时刻
+func anmPoseTimes(duration float32, options AnmPoseOptions) ([]float32, error) {
+	if len(options.Times) > 0 {
+		return options.Times, nil
+	}
+	fps := options.FPS
+	if fps <= 0 {
+		fps = defaultAnmPoseFPS
+	}
+	count := int(math.Floor(float64(duration*fps)+1e-4)) + 1
+	if count > maxAnmPoseSamples {
+		return nil, fmt.Errorf("%d samples requested, more than the %d supported, lower FPS or pass Times", count, maxAnmPoseSamples)
+	}
+	times := make([]float32, count)
+	for i := range times {
+		times[i] = float32(i) / fps
+	}
+	return times, nil
+}
+
+// sampleAnmPoses 没有骨架时按 .anm 中的骨骼采样，没有曲线的分量为 0 或单位四元数
+func sampleAnmPoses(anmData *COM3D2.Anm, times []float32, filter []string) []AnmPose {
+	tracks := anmBoneTracks(anmData)
+	var names []string
+	for _, boneCurve := range anmData.BoneCurves {
+		name := anmBoneName(boneCurve.BonePath)
+		if !slices.Contains(names, name) && (len(filter) == 0 || slices.Contains(filter, name)) {
+			names = append(names, name)
+		}
+	}
+
+	poses := make([]AnmPose, len(times))
+	for i, time := range times {
+		poses[i] = AnmPose{Time: time, Bones: make([]AnmBonePose, len(names))}
+		for b, name := range names {
+			track := tracks[name]
+			position, rotation := track.sample(time, COM3D2.Vector3{}, quatIdentity())
+			poses[i].Bones[b] = AnmBonePose{
+				Name:        name,
+				Path:        track.path,
+				Position:    position,
+				Rotation:    rotation,
+				HasPosition: track.hasPosition(),
+				HasRotation: track.hasRotation(),
+			}
+		}
+	}
+	return poses
+}
+
+// sampleModelPoses 以模型骨架采样，输出模型的所有骨骼并计算世界空间变换
+func sampleModelPoses(anmData *COM3D2.Anm, modelData *COM3D2.Model, times []float32, filter []string) ([]AnmPose, []string) {
+	tracks := anmBoneTracks(anmData)
+	unmatched := []string{}
+	for name := range tracks {
+		if findBone(modelData, name) < 0 {
+			unmatched = append(unmatched, name)
+		}
+	}
+	slices.Sort(unmatched)
+
+	var indices []int
+	paths := make(map[int]string)
+	for i, bone := range modelData.Bones {
+		if len(filter) == 0 || slices.Contains(filter, bone.Name) {
+			indices = append(indices, i)
+			paths[i] = bonePath(modelData.Bones, i)
+		}
+	}
+
+	poses := make([]AnmPose, len(times))
+	for i, time := range times {
+		positions, rotations := sampleLocalPose(tracks, modelData.Bones, time)
+		worldMatrices := poseWorldMatrices(modelData.Bones, positions, rotations)
+		worldRotations := poseWorldRotations(modelData.Bones, rotations)
+
+		poses[i] = AnmPose{Time: time, Bones: make([]AnmBonePose, len(indices))}
+		for b, index := range indices {
+			bone := modelData.Bones[index]
+			pose := AnmBonePose{
+				Name:          bone.Name,
+				Path:          paths[index],
+				Position:      positions[index],
+				Rotation:      rotations[index],
+				WorldPosition: worldMatrices[index].translation(),
+				WorldRotation: worldRotations[index],
+			}
+			if track := tracks[bone.Name]; track != nil {
+				pose.HasPosition, pose.HasRotation = track.hasPosition(), track.hasRotation()
+			}
+			poses[i].Bones[b] = pose
+		}
+	}
+	return poses, unmatched
+}
+
+// poseWorldMatrices 由每个骨骼的局部位置和旋转（缩放取骨骼的静止缩放）计算模型空间的矩阵
+func poseWorldMatrices(bones []*COM3D2.Bone, positions []COM3D2.Vector3, rotations []COM3D2.Quaternion) []mat4 {
+	world := make([]mat4, len(bones))
+	state := make([]uint8, len(bones)) // 0 未计算，1 计算中，2 已完成
+	var compute func(i int) mat4
+	compute = func(i int) mat4 {
+		if state[i] == 2 {
+			return world[i]
+		}
+		state[i] = 1
+		local := mat4TRS(positions[i], rotations[i], boneLocalScale(bones[i]))
+		parent := int(bones[i].ParentIndex)
+		if parent >= 0 && parent < len(bones) && parent != i && state[parent] != 1 {
+			world[i] = mat4Mul(compute(parent), local)
+		} else {
+			world[i] = local
+		}
+		state[i] = 2
+		return world[i]
+	}
+	for i := range bones {
+		compute(i)
+	}
+	return world
+}
+
+// anmBoneTrack 单个骨骼的所有属性曲线，按 PropertyIndex 索引，没有的属性为 nil
+type anmBoneTrack struct {
+	path   string
+	curves [anmPropertyCount][]COM3D2.Keyframe
+}
+
+// hasRotation 是否有旋转曲线
+func (t *anmBoneTrack) hasRotation() bool {
+	for i := anmPropertyRotationX; i <= anmPropertyRotationW; i++ {
+		if len(t.curves[i]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPosition 是否有位置曲线
+func (t *anmBoneTrack) hasPosition() bool {
+	for i := anmPropertyPositionX; i <= anmPropertyPositionZ; i++ {
+		if len(t.curves[i]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sample 计算 time 时刻的局部位置和旋转，没有曲线的分量使用 restPosition 和 restRotation
+func (t *anmBoneTrack) sample(time float32, restPosition COM3D2.Vector3, restRotation COM3D2.Quaternion) (COM3D2.Vector3, COM3D2.Quaternion) {
+	value := func(property int, rest float32) float32 {
+		if len(t.curves[property]) == 0 {
+			return rest
+		}
+		return evaluateKeyframes(t.curves[property], time)
+	}
+	position := COM3D2.Vector3{
+		X: value(anmPropertyPositionX, restPosition.X),
+		Y: value(anmPropertyPositionY, restPosition.Y),
+		Z: value(anmPropertyPositionZ, restPosition.Z),
+	}
+	rotation := quatNormalize(COM3D2.Quaternion{
+		X: value(anmPropertyRotationX, restRotation.X),
+		Y: value(anmPropertyRotationY, restRotation.Y),
+		Z: value(anmPropertyRotationZ, restRotation.Z),
+		W: value(anmPropertyRotationW, restRotation.W),
+	})
+	return position, rotation
+}
+
+// anmBoneTracks 按骨骼名（BonePath 的最后一段）整理曲线
+func anmBoneTracks(anmData *COM3D2.Anm) map[string]*anmBoneTrack {
+	tracks := make(map[string]*anmBoneTrack, len(anmData.BoneCurves))
+	for _, boneCurve := range anmData.BoneCurves {
+		name := anmBoneName(boneCurve.BonePath)
+		track, ok := tracks[name]
+		if !ok {
+			track = &anmBoneTrack{path: boneCurve.BonePath}
+			tracks[name] = track
+		}
+		for _, curve := range boneCurve.PropertyCurves {
+			if curve.PropertyIndex >= 0 && curve.PropertyIndex < anmPropertyCount {
+				track.curves[curve.PropertyIndex] = curve.Keyframes
+			}
+		}
+	}
+	return tracks
+}
+
+// evaluateKeyframes 与 Unity AnimationCurve 一致，使用三次 Hermite 插值计算 time 时刻的值
+// 超出关键帧范围时取两端的值，切线为无穷大时为阶跃
+func evaluateKeyframes(keys []COM3D2.Keyframe, time float32) float32 {
+	n := len(keys)
+	if n == 0 {
+		return 0
+	}
+	if time <= keys[0].Time || n == 1 {
+		return keys[0].Value
+	}
+	if time >= keys[n-1].Time {
+		return keys[n-1].Value
+	}
+
+	// 二分查找 time 所在的区间 [keys[i], keys[i+1]]
+	lo, hi := 0, n-1
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if keys[mid].Time <= time {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	k0, k1 := keys[lo], keys[hi]
+	return hermite(k0, k1, time)
+}
+
+// hermite 在两个关键帧之间插值
+func hermite(k0, k1 COM3D2.Keyframe, time float32) float32 {
+	dt := k1.Time - k0.Time
+	if dt <= 0 {
+		return k1.Value
+	}
+	if isInfinite(k0.OutTangent) || isInfinite(k1.InTangent) {
+		return k0.Value
+	}
+	s := (time - k0.Time) / dt
+	s2 := s * s
+	s3 := s2 * s
+	h00 := 2*s3 - 3*s2 + 1
+	h10 := s3 - 2*s2 + s
+	h01 := -2*s3 + 3*s2
+	h11 := s3 - s2
+	return h00*k0.Value + h10*k0.OutTangent*dt + h01*k1.Value + h11*k1.InTangent*dt
+}
+
+// isInfinite 判断是否为正负无穷
+func isInfinite(f float32) bool {
+	return math.IsInf(float64(f), 0)
+}
+
+// sampleLocalPose 计算 time 时刻每个骨骼的局部位置和旋转，.anm 中没有曲线的骨骼使用静止姿态
+func sampleLocalPose(tracks map[string]*anmBoneTrack, bones []*COM3D2.Bone, time float32) ([]COM3D2.Vector3, []COM3D2.Quaternion) {
+	positions := make([]COM3D2.Vector3, len(bones))
+	rotations := make([]COM3D2.Quaternion, len(bones))
+	for i, bone := range bones {
+		positions[i], rotations[i] = bone.Position, bone.Rotation
+		if track := tracks[bone.Name]; track != nil {
+			positions[i], rotations[i] = track.sample(time, bone.Position, bone.Rotation)
+		}
+	}
+	return positions, rotations
+}
+
+// composeWorldRotations 按父骨骼在前的顺序计算每个骨骼的世界旋转，world 由父骨骼的世界旋转得到该骨骼的世界旋转
+// 父骨骼索引无效或出现循环时，父骨骼的世界旋转视为单位四元数
+func composeWorldRotations(bones []*COM3D2.Bone, world func(i int, parentWorld COM3D2.Quaternion) COM3D2.Quaternion) []COM3D2.Quaternion {
+	result := make([]COM3D2.Quaternion, len(bones))
+	state := make([]uint8, len(bones)) // 0 未计算，1 计算中，2 已完成
+	var compute func(i int) COM3D2.Quaternion
+	compute = func(i int) COM3D2.Quaternion {
+		if state[i] == 2 {
+			return result[i]
+		}
+		state[i] = 1
+		parentWorld := quatIdentity()
+		if parent := int(bones[i].ParentIndex); parent >= 0 && parent < len(bones) && parent != i && state[parent] != 1 {
+			parentWorld = compute(parent)
+		}
+		result[i] = quatNormalize(world(i, parentWorld))
+		state[i] = 2
+		return result[i]
+	}
+	for i := range bones {
+		compute(i)
+	}
+	return result
+}
+
+// poseWorldRotations 由局部旋转计算世界旋转
+func poseWorldRotations(bones []*COM3D2.Bone, local []COM3D2.Quaternion) []COM3D2.Quaternion {
+	return composeWorldRotations(bones, func(i int, parentWorld COM3D2.Quaternion) COM3D2.Quaternion {
+		return quatMul(parentWorld, local[i])
+	})
+}
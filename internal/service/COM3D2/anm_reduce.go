@@ -0,0 +1,222 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 关键帧精简的默认容差
+const (
+	defaultReducePositionTolerance = 0.001 // 位置容差，单位与模型坐标一致（约 1 毫米）
+	defaultReduceRotationTolerance = 0.5   // 旋转容差（度）
+)
+
+// AnmReduceOptions 精简关键帧的选项
+type AnmReduceOptions struct {
+	PositionTolerance float32 `json:"PositionTolerance"` // 精简后位置与原曲线的最大距离，0 时使用默认值 0.001
+	RotationTolerance float32 `json:"RotationTolerance"` // 精简后旋转与原曲线的最大夹角（度），0 时使用默认值 0.5
+}
+
+// AnmReduceResult 精简关键帧的结果
+type AnmReduceResult struct {
+	OriginalKeyframes int   `json:"OriginalKeyframes"` // 原关键帧数
+	ReducedKeyframes  int   `json:"ReducedKeyframes"`  // 精简后的关键帧数
+	OriginalSize      int64 `json:"OriginalSize"`      // 原文件大小（字节）
+	ReducedSize       int64 `json:"ReducedSize"`       // 输出文件大小（字节）
+}
+
+// anmCurveGroup 需要一起精简的曲线，例如同一骨骼的四条旋转曲线，精简后共用相同的关键帧时刻
+type anmCurveGroup struct {
+	curves    []*COM3D2.PropertyCurve
+	rotation  bool    // 为 true 时按四元数夹角计算误差，否则按距离
+	tolerance float32 // 旋转为弧度
+}
+
+// ReduceAnmKeyframes 移除对曲线形状影响在容差以内的关键帧，写入 outputPath 并报告文件大小的变化
+// 旋转按整个四元数的夹角、位置按三维距离计算误差，保留的关键帧的切线按原曲线在该时刻两侧的斜率重新计算
+func (m *AnmService) ReduceAnmKeyframes(inputPath string, outputPath string, options AnmReduceOptions) (result AnmReduceResult, err error) {
+	anmData, err := m.ReadAnmFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read anm file: %w", err)
+	}
+	result.OriginalKeyframes = anmEditResult(anmData).KeyframeCount
+	if err := reduceAnmKeyframes(anmData, options); err != nil {
+		return result, err
+	}
+	result.ReducedKeyframes = anmEditResult(anmData).KeyframeCount
+
+	if err := m.WriteAnmFile(outputPath, anmData); err != nil {
+		return result, fmt.Errorf("failed to write anm file: %w", err)
+	}
+	if info, err := os.Stat(inputPath); err == nil {
+		result.OriginalSize = info.Size()
+	}
+	if info, err := os.Stat(outputPath); err == nil {
+		result.ReducedSize = info.Size()
+	}
+	return result, nil
+}
+
+// reduceAnmKeyframes 按骨骼将曲线分为旋转组和位置组后分别精简
+func reduceAnmKeyframes(anmData *COM3D2.Anm, options AnmReduceOptions) error {
+	positionTolerance := options.PositionTolerance
+	if positionTolerance == 0 {
+		positionTolerance = defaultReducePositionTolerance
+	}
+	rotationTolerance := options.RotationTolerance
+	if rotationTolerance == 0 {
+		rotationTolerance = defaultReduceRotationTolerance
+	}
+	if positionTolerance < 0 || rotationTolerance < 0 {
+		return fmt.Errorf("tolerances cannot be negative")
+	}
+
+	for b := range anmData.BoneCurves {
+		rotation := anmCurveGroup{rotation: true, tolerance: rotationTolerance * math.Pi / 180}
+		position := anmCurveGroup{tolerance: positionTolerance}
+		curves := anmData.BoneCurves[b].PropertyCurves
+		for c := range curves {
+			switch {
+			case len(curves[c].Keyframes) == 0:
+			case curves[c].PropertyIndex >= anmPropertyRotationX && curves[c].PropertyIndex <= anmPropertyRotationW:
+				rotation.curves = append(rotation.curves, &curves[c])
+			case curves[c].PropertyIndex >= anmPropertyPositionX && curves[c].PropertyIndex <= anmPropertyPositionZ:
+				position.curves = append(position.curves, &curves[c])
+			}
+		}
+		reduceCurveGroup(rotation)
+		reduceCurveGroup(position)
+	}
+	return nil
+}
+
+// reduceCurveGroup 用 Douglas-Peucker 的方式精简一组曲线：
+// 从首尾两个关键帧开始，若区间内原关键帧及相邻关键帧中点处的误差超过容差，则在误差最大处保留关键帧并拆分区间
+func reduceCurveGroup(group anmCurveGroup) {
+	if len(group.curves) == 0 {
+		return
+	}
+
+	// 所有曲线关键帧时刻的并集
+	var times []float32
+	for _, curve := range group.curves {
+		for _, key := range curve.Keyframes {
+			times = append(times, key.Time)
+		}
+	}
+	slices.Sort(times)
+	times = slices.CompactFunc(times, func(a, b float32) bool { return math.Abs(float64(b-a)) < keyframeTimeEpsilon })
+	n := len(times)
+	if n <= 2 {
+		return
+	}
+
+	// 原曲线在各关键帧时刻及中点处的值，以及各关键帧时刻两侧的斜率
+	values := make([][]float32, n)
+	mids := make([][]float32, n-1)
+	keys := make([][]COM3D2.Keyframe, n)
+	for i, time := range times {
+		values[i] = make([]float32, len(group.curves))
+		keys[i] = make([]COM3D2.Keyframe, len(group.curves))
+		for c, curve := range group.curves {
+			value := evaluateKeyframes(curve.Keyframes, time)
+			in, out := keyframeSlopes(curve.Keyframes, time)
+			values[i][c] = value
+			keys[i][c] = COM3D2.Keyframe{Time: time, Value: value, InTangent: in, OutTangent: out}
+		}
+		if i+1 < n {
+			mid := (time + times[i+1]) / 2
+			mids[i] = make([]float32, len(group.curves))
+			for c, curve := range group.curves {
+				mids[i][c] = evaluateKeyframes(curve.Keyframes, mid)
+			}
+		}
+	}
+
+	approx := make([]float32, len(group.curves))
+	segmentError := func(a, b int, time float32, original []float32) float32 {
+		for c := range group.curves {
+			approx[c] = hermite(keys[a][c], keys[b][c], time)
+		}
+		return group.error(original, approx)
+	}
+
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		a, b := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+		if b-a < 2 {
+			continue
+		}
+		worst, worstError := -1, group.tolerance
+		for k := a; k < b; k++ {
+			if k > a {
+				if e := segmentError(a, b, times[k], values[k]); e > worstError {
+					worst, worstError = k, e
+				}
+			}
+			if e := segmentError(a, b, (times[k]+times[k+1])/2, mids[k]); e > worstError {
+				// 中点不是关键帧，取它两侧位于区间内部的关键帧
+				worst, worstError = k, e
+				if k == a {
+					worst = k + 1
+				}
+			}
+		}
+		if worst < 0 {
+			continue
+		}
+		keep[worst] = true
+		stack = append(stack, [2]int{a, worst}, [2]int{worst, b})
+	}
+
+	for c, curve := range group.curves {
+		reduced := make([]COM3D2.Keyframe, 0, n)
+		for i := range times {
+			if keep[i] {
+				reduced = append(reduced, keys[i][c])
+			}
+		}
+		curve.Keyframes = reduced
+	}
+}
+
+// error 计算精简后的值与原值的误差，旋转组缺少的分量视为单位四元数的分量
+func (g anmCurveGroup) error(original []float32, approx []float32) float32 {
+	if !g.rotation {
+		var sum float32
+		for c := range original {
+			d := original[c] - approx[c]
+			sum += d * d
+		}
+		return float32(math.Sqrt(float64(sum)))
+	}
+	q0, q1 := quatIdentity(), quatIdentity()
+	for c, curve := range g.curves {
+		setQuaternionComponent(&q0, curve.PropertyIndex, original[c])
+		setQuaternionComponent(&q1, curve.PropertyIndex, approx[c])
+	}
+	q0, q1 = quatNormalize(q0), quatNormalize(q1)
+	dot := math.Abs(float64(q0.X*q1.X + q0.Y*q1.Y + q0.Z*q1.Z + q0.W*q1.W))
+	return float32(2 * math.Acos(math.Min(1, dot)))
+}
+
+// setQuaternionComponent 按 PropertyIndex 设置四元数的分量
+func setQuaternionComponent(q *COM3D2.Quaternion, property int, value float32) {
+	switch property {
+	case anmPropertyRotationX:
+		q.X = value
+	case anmPropertyRotationY:
+		q.Y = value
+	case anmPropertyRotationZ:
+		q.Z = value
+	case anmPropertyRotationW:
+		q.W = value
+	}
+}
@@ -0,0 +1,254 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// AnmEditResult 编辑动画后的统计
+type AnmEditResult struct {
+	Duration      float32 `json:"Duration"`      // 编辑后的动画长度（秒）
+	CurveCount    int     `json:"CurveCount"`    // 属性曲线数
+	KeyframeCount int     `json:"KeyframeCount"` // 关键帧总数
+}
+
+// AnmLoopOptions 制作循环动画的选项
+type AnmLoopOptions struct {
+	BlendDuration  float32 `json:"BlendDuration"`  // 在结尾的这段时间（秒）内逐渐过渡到开头的姿态，0 时只修改最后一个关键帧
+	KeepRootMotion bool    `json:"KeepRootMotion"` // 为 true 时根骨骼的位置曲线保持不变，用于行走等需要整体位移的动作
+}
+
+// ScaleAnmTime 将所有关键帧的时间乘以 factor，大于 1 时变慢，小于 1 时变快
+func (m *AnmService) ScaleAnmTime(inputPath string, outputPath string, factor float32) (AnmEditResult, error) {
+	return m.editAnm(inputPath, outputPath, func(anmData *COM3D2.Anm) error {
+		return scaleAnmTime(anmData, factor)
+	})
+}
+
+// TrimAnm 只保留 [start, end] 秒之间的动作并从 0 开始，区间端点处会插入关键帧以保持曲线形状
+// end 超过动画长度时按动画长度处理
+func (m *AnmService) TrimAnm(inputPath string, outputPath string, start float32, end float32) (AnmEditResult, error) {
+	return m.editAnm(inputPath, outputPath, func(anmData *COM3D2.Anm) error {
+		return trimAnm(anmData, start, end)
+	})
+}
+
+// OffsetAnm 将所有关键帧的时间加上 offset 秒，为正时在开头保持第一帧的姿态，为负时裁掉开头的部分
+func (m *AnmService) OffsetAnm(inputPath string, outputPath string, offset float32) (AnmEditResult, error) {
+	return m.editAnm(inputPath, outputPath, func(anmData *COM3D2.Anm) error {
+		return offsetAnm(anmData, offset)
+	})
+}
+
+// ReverseAnm 倒放动画
+func (m *AnmService) ReverseAnm(inputPath string, outputPath string) (AnmEditResult, error) {
+	return m.editAnm(inputPath, outputPath, func(anmData *COM3D2.Anm) error {
+		reverseAnm(anmData)
+		return nil
+	})
+}
+
+// LoopAnm 使所有曲线最后一帧的值与第一帧一致，动画循环播放时不会跳变
+func (m *AnmService) LoopAnm(inputPath string, outputPath string, options AnmLoopOptions) (AnmEditResult, error) {
+	return m.editAnm(inputPath, outputPath, func(anmData *COM3D2.Anm) error {
+		return loopAnm(anmData, options)
+	})
+}
+
+// editAnm 读取动画，调用 edit 修改后写入 outputPath
+func (m *AnmService) editAnm(inputPath string, outputPath string, edit func(anmData *COM3D2.Anm) error) (AnmEditResult, error) {
+	anmData, err := m.ReadAnmFile(inputPath)
+	if err != nil {
+		return AnmEditResult{}, fmt.Errorf("failed to read anm file: %w", err)
+	}
+	if err := edit(anmData); err != nil {
+		return AnmEditResult{}, err
+	}
+	if err := m.WriteAnmFile(outputPath, anmData); err != nil {
+		return AnmEditResult{}, fmt.Errorf("failed to write anm file: %w", err)
+	}
+	return anmEditResult(anmData), nil
+}
+
+// anmEditResult 统计动画的长度、曲线数和关键帧数
+func anmEditResult(anmData *COM3D2.Anm) AnmEditResult {
+	result := AnmEditResult{Duration: anmDuration(anmData)}
+	for _, boneCurve := range anmData.BoneCurves {
+		for _, curve := range boneCurve.PropertyCurves {
+			result.CurveCount++
+			result.KeyframeCount += len(curve.Keyframes)
+		}
+	}
+	return result
+}
+
+// forEachAnmCurve 对每条属性曲线调用 edit，并用其返回值替换原关键帧
+func forEachAnmCurve(anmData *COM3D2.Anm, edit func(keys []COM3D2.Keyframe) []COM3D2.Keyframe) {
+	for b := range anmData.BoneCurves {
+		curves := anmData.BoneCurves[b].PropertyCurves
+		for c := range curves {
+			curves[c].Keyframes = edit(curves[c].Keyframes)
+		}
+	}
+}
+
+// scaleAnmTime 缩放时间，切线（每秒的变化量）按相反比例缩放
+func scaleAnmTime(anmData *COM3D2.Anm, factor float32) error {
+	if !(factor > 0) || isInfinite(factor) {
+		return fmt.Errorf("time scale factor must be positive, got %v", factor)
+	}
+	forEachAnmCurve(anmData, func(keys []COM3D2.Keyframe) []COM3D2.Keyframe {
+		for i := range keys {
+			keys[i].Time *= factor
+			keys[i].InTangent /= factor
+			keys[i].OutTangent /= factor
+		}
+		return keys
+	})
+	return nil
+}
+
+// trimAnm 裁剪到 [start, end] 并平移到 0
+func trimAnm(anmData *COM3D2.Anm, start float32, end float32) error {
+	end = min(end, anmDuration(anmData))
+	if start < 0 || !(end > start) {
+		return fmt.Errorf("invalid trim range [%v, %v]", start, end)
+	}
+	forEachAnmCurve(anmData, func(keys []COM3D2.Keyframe) []COM3D2.Keyframe {
+		if len(keys) == 0 {
+			return keys
+		}
+		keys = insertKeyframe(keys, start)
+		keys = insertKeyframe(keys, end)
+		kept := keys[:0]
+		for _, key := range keys {
+			if key.Time >= start-keyframeTimeEpsilon && key.Time <= end+keyframeTimeEpsilon {
+				key.Time = max(key.Time-start, 0)
+				kept = append(kept, key)
+			}
+		}
+		return kept
+	})
+	return nil
+}
+
+// offsetAnm 平移时间，负的偏移会裁掉 0 之前的部分
+func offsetAnm(anmData *COM3D2.Anm, offset float32) error {
+	if math.IsNaN(float64(offset)) || isInfinite(offset) {
+		return fmt.Errorf("invalid time offset %v", offset)
+	}
+	if offset < 0 {
+		duration := anmDuration(anmData)
+		if -offset >= duration {
+			return fmt.Errorf("time offset %v removes the whole animation of %v seconds", offset, duration)
+		}
+		return trimAnm(anmData, -offset, duration)
+	}
+	forEachAnmCurve(anmData, func(keys []COM3D2.Keyframe) []COM3D2.Keyframe {
+		for i := range keys {
+			keys[i].Time += offset
+		}
+		return keys
+	})
+	return nil
+}
+
+// reverseAnm 倒放：t' = duration - t，关键帧顺序反转，入切线与出切线交换并取反
+func reverseAnm(anmData *COM3D2.Anm) {
+	duration := anmDuration(anmData)
+	forEachAnmCurve(anmData, func(keys []COM3D2.Keyframe) []COM3D2.Keyframe {
+		reversed := make([]COM3D2.Keyframe, len(keys))
+		for i, key := range keys {
+			reversed[len(keys)-1-i] = COM3D2.Keyframe{
+				Time:       duration - key.Time,
+				Value:      key.Value,
+				InTangent:  -key.OutTangent,
+				OutTangent: -key.InTangent,
+			}
+		}
+		return reversed
+	})
+}
+
+// loopAnm 使每条曲线在动画结尾的值与开头一致
+// 旋转曲线若首尾四元数位于不同半球，则结尾匹配开头四元数的相反数，二者表示同一旋转
+func loopAnm(anmData *COM3D2.Anm, options AnmLoopOptions) error {
+	duration := anmDuration(anmData)
+	if duration <= 0 {
+		return fmt.Errorf("animation has no length to loop")
+	}
+	blend := options.BlendDuration
+	if blend < 0 || math.IsNaN(float64(blend)) {
+		return fmt.Errorf("invalid blend duration %v", blend)
+	}
+	blend = min(blend, duration)
+
+	for b := range anmData.BoneCurves {
+		boneCurve := &anmData.BoneCurves[b]
+		isRoot := !strings.Contains(boneCurve.BonePath, "/")
+
+		// 首尾旋转的半球
+		var dot float32
+		for _, curve := range boneCurve.PropertyCurves {
+			if curve.PropertyIndex >= anmPropertyRotationX && curve.PropertyIndex <= anmPropertyRotationW {
+				dot += evaluateKeyframes(curve.Keyframes, 0) * evaluateKeyframes(curve.Keyframes, duration)
+			}
+		}
+
+		for c := range boneCurve.PropertyCurves {
+			curve := &boneCurve.PropertyCurves[c]
+			isRotation := curve.PropertyIndex >= anmPropertyRotationX && curve.PropertyIndex <= anmPropertyRotationW
+			if len(curve.Keyframes) == 0 || (options.KeepRootMotion && isRoot && !isRotation) {
+				continue
+			}
+			sign := float32(1)
+			if isRotation && dot < 0 {
+				sign = -1
+			}
+			curve.Keyframes = loopKeyframes(curve.Keyframes, duration, blend, sign)
+		}
+	}
+	return nil
+}
+
+// loopKeyframes 使曲线在 duration 处的值等于开头的值乘以 sign
+// 差值在 [duration-blend, duration] 内按 smoothstep 逐渐叠加，区间之前的部分保持不变
+func loopKeyframes(keys []COM3D2.Keyframe, duration float32, blend float32, sign float32) []COM3D2.Keyframe {
+	keys = insertKeyframe(keys, 0)
+	keys = insertKeyframe(keys, duration)
+	if blend > 0 {
+		keys = insertKeyframe(keys, duration-blend)
+	}
+	first, last := keys[0], len(keys)-1
+	diff := sign*first.Value - keys[last].Value
+
+	if blend > 0 {
+		blendStart := duration - blend
+		for i := range keys {
+			if keys[i].Time <= blendStart+keyframeTimeEpsilon {
+				continue
+			}
+			u := min((keys[i].Time-blendStart)/blend, 1)
+			offset := diff * (3*u*u - 2*u*u*u)
+			slope := diff * (6*u - 6*u*u) / blend
+			keys[i].Value += offset
+			if !isInfinite(keys[i].InTangent) {
+				keys[i].InTangent += slope
+			}
+			if !isInfinite(keys[i].OutTangent) {
+				keys[i].OutTangent += slope
+			}
+		}
+	}
+	keys[last].Value = sign * first.Value
+
+	// 结尾的斜率与开头一致，循环时速度连续
+	if !isInfinite(first.OutTangent) && !isInfinite(keys[last].InTangent) {
+		keys[last].InTangent = sign * first.OutTangent
+		keys[last].OutTangent = sign * first.OutTangent
+	}
+	return keys
+}
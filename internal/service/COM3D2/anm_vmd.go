@@ -0,0 +1,717 @@
+package COM3D2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// VMD 相关常量
+const (
+	vmdFPS                = 30                          // VMD 的帧率固定为 30
+	vmdSignature          = "Vocaloid Motion Data 0002" // 新格式，模型名 20 字节
+	vmdLegacySignature    = "Vocaloid Motion Data file" // 旧格式，模型名 10 字节
+	vmdBoneNameSize       = 15
+	vmdBoneFrameSize      = 111
+	defaultVmdUnitScale   = 0.08 // 1 个 MMD 单位约为 8 厘米
+	defaultVmdExportModel = "COM3D2"
+	vmdLeftArm            = "左腕"
+	vmdRightArm           = "右腕"
+)
+
+// VmdBoneMapping MMD 骨骼与 COM3D2 骨骼的对应关系
+// MMD 骨骼没有朝向，旋转都是相对于静止姿态的增量，因此需要 MMD 侧的层级来计算世界空间的旋转
+type VmdBoneMapping struct {
+	MMD       string `json:"MMD"`       // MMD 骨骼名
+	MMDParent string `json:"MMDParent"` // MMD 中的父骨骼，为空表示根骨骼
+	COM3D2    string `json:"COM3D2"`    // 对应的 COM3D2 骨骼名，为空表示只参与层级计算，不写入动作
+	Position  bool   `json:"Position"`  // 是否转换该骨骼的位置，一般只有センター等根骨骼需要
+}
+
+// VmdImportOptions 导入 VMD 的选项
+type VmdImportOptions struct {
+	FPS       float32          `json:"FPS"`       // 输出 .anm 的采样帧率，0 时使用 VMD 的 30
+	UnitScale float32          `json:"UnitScale"` // 1 个 MMD 单位对应的游戏单位，0 时使用默认值 0.08
+	ArmAngle  float32          `json:"ArmAngle"`  // MMD 模型静止姿态中手臂向下的角度（度），COM3D2 身体为 T 姿态，大多数 MMD 模型约为 35
+	BoneMap   []VmdBoneMapping `json:"BoneMap"`   // 骨骼映射表，为空时使用 GetDefaultVmdBoneMap
+}
+
+// VmdImportResult 导入 VMD 的结果
+type VmdImportResult struct {
+	FrameCount    int      `json:"FrameCount"`    // 输出的帧数
+	ImportedBones []string `json:"ImportedBones"` // 写入 .anm 的 COM3D2 骨骼
+	IgnoredBones  []string `json:"IgnoredBones"`  // VMD 中有关键帧但不在映射表中的 MMD 骨骼，例如 IK 骨骼
+	MissingBones  []string `json:"MissingBones"`  // 映射表中有、但模型中没有的 COM3D2 骨骼
+}
+
+// VmdExportOptions 导出 VMD 的选项
+type VmdExportOptions struct {
+	ModelName string           `json:"ModelName"` // 写入 VMD 的模型名，为空时为 COM3D2
+	UnitScale float32          `json:"UnitScale"` // 1 个 MMD 单位对应的游戏单位，0 时使用默认值 0.08
+	ArmAngle  float32          `json:"ArmAngle"`  // MMD 模型静止姿态中手臂向下的角度（度），与导入时含义相同
+	BoneMap   []VmdBoneMapping `json:"BoneMap"`   // 骨骼映射表，为空时使用 GetDefaultVmdBoneMap
+}
+
+// VmdExportResult 导出 VMD 的结果
+type VmdExportResult struct {
+	FrameCount    int      `json:"FrameCount"`    // 每个骨骼导出的帧数
+	ExportedBones []string `json:"ExportedBones"` // 写入 VMD 的 MMD 骨骼
+	MissingBones  []string `json:"MissingBones"`  // 映射表中有、但模型中没有的 COM3D2 骨骼
+}
+
+// vmdBoneFrame VMD 中的一个骨骼关键帧，位置和旋转为 MMD 坐标
+type vmdBoneFrame struct {
+	name          string
+	frame         uint32
+	position      COM3D2.Vector3
+	rotation      COM3D2.Quaternion
+	interpolation [64]byte // 贝塞尔插值参数，第一行依次为 X、Y、Z、旋转的 x1、y1、x2、y2
+}
+
+// vmdMotion 解析后的 VMD 文件，只包含骨骼关键帧
+type vmdMotion struct {
+	modelName  string
+	boneFrames []vmdBoneFrame
+}
+
+// vmdBoneNode 映射表中的一个骨骼，按 MMD 层级计算时使用
+type vmdBoneNode struct {
+	mapping    VmdBoneMapping
+	parent     int // 在映射表中的父骨骼索引，-1 为根骨骼
+	bone       int // 对应的模型骨骼索引，-1 为没有
+	correction COM3D2.Quaternion
+}
+
+// GetDefaultVmdBoneMap 返回默认的 MMD 标准骨骼到 COM3D2 骨骼的映射表，可修改后传入导入导出选项
+func (m *AnmService) GetDefaultVmdBoneMap() []VmdBoneMapping {
+	return defaultVmdBoneMap()
+}
+
+// ImportVmdToAnm 读取 VMD 的骨骼关键帧，按映射表转换为以 modelPath 为骨架的 .anm 并写入 outputPath
+// 假定 MMD 模型与 COM3D2 身体的静止姿态一致（手臂角度可由 ArmAngle 修正），IK 不会被解算，使用 IK 的动作需要先在 MMD 中烘焙为 FK
+func (m *AnmService) ImportVmdToAnm(inputPath string, modelPath string, outputPath string, options VmdImportOptions) (result VmdImportResult, err error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("cannot open vmd file: %w", err)
+	}
+	defer f.Close()
+
+	motion, err := parseVmd(bufio.NewReader(f))
+	if err != nil {
+		return result, fmt.Errorf("parsing the vmd file failed: %w", err)
+	}
+	modelData, err := (&ModelService{}).ReadModelFile(modelPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	anmData, result, err := vmdToAnm(motion, modelData, options)
+	if err != nil {
+		return result, err
+	}
+	if err := m.WriteAnmFile(outputPath, anmData); err != nil {
+		return result, fmt.Errorf("failed to write anm file: %w", err)
+	}
+	return result, nil
+}
+
+// ExportAnmToVmd 以 modelPath 的骨架为准，将 .anm 按 30 帧每秒采样后导出为 VMD 的骨骼关键帧
+func (m *AnmService) ExportAnmToVmd(inputPath string, modelPath string, outputPath string, options VmdExportOptions) (result VmdExportResult, err error) {
+	anmData, err := m.ReadAnmFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read anm file: %w", err)
+	}
+	modelData, err := (&ModelService{}).ReadModelFile(modelPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	motion, result, err := anmToVmd(anmData, modelData, options)
+	if err != nil {
+		return result, err
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return result, fmt.Errorf("unable to create vmd file: %w", err)
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	if err := writeVmd(bw, motion); err != nil {
+		return result, fmt.Errorf("failed to write vmd file: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return result, fmt.Errorf("an error occurred while flush bufio: %w", err)
+	}
+	return result, nil
+}
+
+// vmdToAnm 将 VMD 骨骼关键帧转换为 Anm
+func vmdToAnm(motion *vmdMotion, modelData *COM3D2.Model, options VmdImportOptions) (*COM3D2.Anm, VmdImportResult, error) {
+	result := VmdImportResult{ImportedBones: []string{}, IgnoredBones: []string{}}
+	fps := options.FPS
+	if fps <= 0 {
+		fps = vmdFPS
+	}
+	scale := options.UnitScale
+	if scale <= 0 {
+		scale = defaultVmdUnitScale
+	}
+	nodes, missing, err := buildVmdBoneNodes(options.BoneMap, modelData, options.ArmAngle)
+	if err != nil {
+		return nil, result, err
+	}
+	result.MissingBones = missing
+
+	// 按骨骼名整理关键帧
+	tracks := make(map[string][]vmdBoneFrame)
+	var lastFrame uint32
+	for _, frame := range motion.boneFrames {
+		tracks[frame.name] = append(tracks[frame.name], frame)
+		lastFrame = max(lastFrame, frame.frame)
+	}
+	for name, track := range tracks {
+		sort.SliceStable(track, func(i, j int) bool { return track[i].frame < track[j].frame })
+		if !slices.ContainsFunc(nodes, func(node vmdBoneNode) bool { return node.mapping.MMD == name }) {
+			result.IgnoredBones = append(result.IgnoredBones, name)
+		}
+	}
+	slices.Sort(result.IgnoredBones)
+
+	restWorld := boneWorldRotations(modelData.Bones)
+	frameCount := int(math.Floor(float64(lastFrame)/vmdFPS*float64(fps)+1e-4)) + 1
+	times := make([]float32, frameCount)
+	positions := make(map[int][]COM3D2.Vector3)
+	rotations := make(map[int][]COM3D2.Quaternion)
+	for _, node := range nodes {
+		if node.bone < 0 {
+			continue
+		}
+		rotations[node.bone] = make([]COM3D2.Quaternion, frameCount)
+		if node.mapping.Position {
+			positions[node.bone] = make([]COM3D2.Vector3, frameCount)
+		}
+	}
+
+	for frame := 0; frame < frameCount; frame++ {
+		times[frame] = float32(frame) / fps
+		vmdFrame := times[frame] * vmdFPS
+
+		// MMD 侧的世界旋转增量和位置增量（已转换到 Unity 坐标系）
+		worldRotations, worldPositions := vmdNodeWorld(nodes, func(i int) (COM3D2.Vector3, COM3D2.Quaternion) {
+			position, rotation := sampleVmdTrack(tracks[nodes[i].mapping.MMD], vmdFrame)
+			return vec3Scale(mmdToUnityPosition(position), scale), mmdToUnityRotation(rotation)
+		})
+
+		targetWorld := make(map[int]COM3D2.Quaternion, len(rotations))
+		for i, node := range nodes {
+			if node.bone < 0 {
+				continue
+			}
+			targetWorld[node.bone] = quatMul(quatMul(worldRotations[i], node.correction), restWorld[node.bone])
+			if p := positions[node.bone]; p != nil {
+				bone := modelData.Bones[node.bone]
+				p[frame] = vec3Add(bone.Position, quatRotate(quatConjugate(parentRestWorld(modelData.Bones, restWorld, node.bone)), worldPositions[i]))
+			}
+		}
+		for index, local := range retargetLocalRotations(modelData.Bones, targetWorld) {
+			rotations[index][frame] = local
+		}
+	}
+
+	anmData := &COM3D2.Anm{
+		Signature: "CM3D2_ANIM",
+		Version:   defaultAnmVersion,
+	}
+	for _, node := range nodes {
+		if node.bone < 0 {
+			continue
+		}
+		boneCurve := sampledBoneCurve(bonePath(modelData.Bones, node.bone), times, positions[node.bone], rotations[node.bone])
+		anmData.BoneCurves = append(anmData.BoneCurves, boneCurve)
+		result.ImportedBones = append(result.ImportedBones, modelData.Bones[node.bone].Name)
+	}
+	result.FrameCount = frameCount
+	return anmData, result, nil
+}
+
+// anmToVmd 将 Anm 转换为 VMD 骨骼关键帧，每个骨骼每帧一个关键帧，使用线性插值
+func anmToVmd(anmData *COM3D2.Anm, modelData *COM3D2.Model, options VmdExportOptions) (*vmdMotion, VmdExportResult, error) {
+	result := VmdExportResult{ExportedBones: []string{}}
+	scale := options.UnitScale
+	if scale <= 0 {
+		scale = defaultVmdUnitScale
+	}
+	nodes, missing, err := buildVmdBoneNodes(options.BoneMap, modelData, options.ArmAngle)
+	if err != nil {
+		return nil, result, err
+	}
+	result.MissingBones = missing
+
+	motion := &vmdMotion{modelName: options.ModelName}
+	if motion.modelName == "" {
+		motion.modelName = defaultVmdExportModel
+	}
+	for _, node := range nodes {
+		if node.bone >= 0 {
+			result.ExportedBones = append(result.ExportedBones, node.mapping.MMD)
+		}
+	}
+
+	tracks := anmBoneTracks(anmData)
+	restWorld := boneWorldRotations(modelData.Bones)
+	result.FrameCount = int(math.Floor(float64(anmDuration(anmData)*vmdFPS)+1e-4)) + 1
+	for frame := 0; frame < result.FrameCount; frame++ {
+		positions, locals := sampleLocalPose(tracks, modelData.Bones, float32(frame)/vmdFPS)
+		world := poseWorldRotations(modelData.Bones, locals)
+
+		// MMD 侧的世界旋转增量：D = W * R0^-1 * C^-1，没有对应骨骼的 MMD 骨骼保持静止
+		worldRotations := make([]COM3D2.Quaternion, len(nodes))
+		worldPositions := make([]COM3D2.Vector3, len(nodes))
+		for i, node := range nodes {
+			worldRotations[i] = quatIdentity()
+			if node.bone >= 0 {
+				worldRotations[i] = quatNormalize(quatMul(quatMul(world[node.bone], quatConjugate(restWorld[node.bone])), quatConjugate(node.correction)))
+				if node.mapping.Position {
+					delta := vec3Sub(positions[node.bone], modelData.Bones[node.bone].Position)
+					worldPositions[i] = quatRotate(parentRestWorld(modelData.Bones, restWorld, node.bone), delta)
+				}
+			}
+		}
+		localRotations, localPositions := vmdNodeLocal(nodes, worldRotations, worldPositions)
+
+		for i, node := range nodes {
+			if node.bone < 0 {
+				continue
+			}
+			boneFrame := vmdBoneFrame{
+				name:     node.mapping.MMD,
+				frame:    uint32(frame),
+				rotation: unityToMmdRotation(localRotations[i]),
+			}
+			if node.mapping.Position {
+				boneFrame.position = unityToMmdPosition(vec3Scale(localPositions[i], 1/scale))
+			}
+			boneFrame.interpolation = vmdLinearInterpolation()
+			motion.boneFrames = append(motion.boneFrames, boneFrame)
+		}
+	}
+	return motion, result, nil
+}
+
+// buildVmdBoneNodes 整理映射表，确定每个 MMD 骨骼的父骨骼、对应的模型骨骼和手臂角度修正
+// 多个 MMD 骨骼对应同一个 COM3D2 骨骼时只使用第一个
+func buildVmdBoneNodes(boneMap []VmdBoneMapping, modelData *COM3D2.Model, armAngle float32) ([]vmdBoneNode, []string, error) {
+	if len(boneMap) == 0 {
+		boneMap = defaultVmdBoneMap()
+	}
+	missing := []string{}
+	nodes := make([]vmdBoneNode, len(boneMap))
+	byName := make(map[string]int, len(boneMap))
+	usedBones := make(map[int]bool)
+	for i, mapping := range boneMap {
+		if mapping.MMD == "" {
+			return nil, nil, fmt.Errorf("bone map entry %d has no MMD bone name", i)
+		}
+		if _, ok := byName[mapping.MMD]; ok {
+			return nil, nil, fmt.Errorf("MMD bone %q appears more than once in bone map", mapping.MMD)
+		}
+		byName[mapping.MMD] = i
+		nodes[i] = vmdBoneNode{mapping: mapping, parent: -1, bone: -1, correction: quatIdentity()}
+		if mapping.COM3D2 == "" {
+			continue
+		}
+		index := findBone(modelData, mapping.COM3D2)
+		if index < 0 {
+			missing = append(missing, mapping.COM3D2)
+			continue
+		}
+		if !usedBones[index] {
+			nodes[i].bone = index
+			usedBones[index] = true
+		}
+	}
+	for i := range nodes {
+		if parent := nodes[i].mapping.MMDParent; parent != "" {
+			p, ok := byName[parent]
+			if !ok {
+				return nil, nil, fmt.Errorf("parent %q of MMD bone %q not found in bone map", parent, nodes[i].mapping.MMD)
+			}
+			nodes[i].parent = p
+		}
+	}
+
+	// MMD 的手臂在静止姿态中向下倾斜，修正作用于手臂及其所有子骨骼；Unity 中角色面向 +Z，左臂朝向 -X
+	angle := float64(armAngle) * math.Pi / 180
+	var resolve func(i int, depth int) COM3D2.Quaternion
+	resolve = func(i int, depth int) COM3D2.Quaternion {
+		switch {
+		case nodes[i].mapping.MMD == vmdLeftArm:
+			return quatFromAxisAngle(COM3D2.Vector3{Z: 1}, angle)
+		case nodes[i].mapping.MMD == vmdRightArm:
+			return quatFromAxisAngle(COM3D2.Vector3{Z: 1}, -angle)
+		case nodes[i].parent < 0 || depth > len(nodes):
+			return quatIdentity()
+		}
+		return resolve(nodes[i].parent, depth+1)
+	}
+	for i := range nodes {
+		if nodes[i].parent == i || isVmdNodeCycle(nodes, i) {
+			return nil, nil, fmt.Errorf("MMD bone %q is its own ancestor in bone map", nodes[i].mapping.MMD)
+		}
+		if armAngle != 0 {
+			nodes[i].correction = resolve(i, 0)
+		}
+	}
+	return nodes, missing, nil
+}
+
+// isVmdNodeCycle 判断映射表的父骨骼链中是否有循环
+func isVmdNodeCycle(nodes []vmdBoneNode, i int) bool {
+	for steps, p := 0, nodes[i].parent; p >= 0; steps, p = steps+1, nodes[p].parent {
+		if p == i || steps > len(nodes) {
+			return true
+		}
+	}
+	return false
+}
+
+// vmdNodeWorld 由每个 MMD 骨骼的局部位置和旋转增量，按 MMD 层级计算世界空间的增量
+func vmdNodeWorld(nodes []vmdBoneNode, local func(i int) (COM3D2.Vector3, COM3D2.Quaternion)) ([]COM3D2.Quaternion, []COM3D2.Vector3) {
+	rotations := make([]COM3D2.Quaternion, len(nodes))
+	positions := make([]COM3D2.Vector3, len(nodes))
+	done := make([]bool, len(nodes))
+	var compute func(i int)
+	compute = func(i int) {
+		if done[i] {
+			return
+		}
+		position, rotation := local(i)
+		parentRotation := quatIdentity()
+		var parentPosition COM3D2.Vector3
+		if p := nodes[i].parent; p >= 0 {
+			compute(p)
+			parentRotation, parentPosition = rotations[p], positions[p]
+		}
+		rotations[i] = quatNormalize(quatMul(parentRotation, rotation))
+		positions[i] = vec3Add(parentPosition, quatRotate(parentRotation, position))
+		done[i] = true
+	}
+	for i := range nodes {
+		compute(i)
+	}
+	return rotations, positions
+}
+
+// vmdNodeLocal 是 vmdNodeWorld 的逆运算，没有对应骨骼的 MMD 骨骼的世界增量取父骨骼的值
+func vmdNodeLocal(nodes []vmdBoneNode, worldRotations []COM3D2.Quaternion, worldPositions []COM3D2.Vector3) ([]COM3D2.Quaternion, []COM3D2.Vector3) {
+	rotations := make([]COM3D2.Quaternion, len(nodes))
+	positions := make([]COM3D2.Vector3, len(nodes))
+	done := make([]bool, len(nodes))
+	var compute func(i int)
+	compute = func(i int) {
+		if done[i] {
+			return
+		}
+		parentRotation := quatIdentity()
+		var parentPosition COM3D2.Vector3
+		if p := nodes[i].parent; p >= 0 {
+			compute(p)
+			parentRotation, parentPosition = worldRotations[p], worldPositions[p]
+		}
+		if nodes[i].bone < 0 {
+			worldRotations[i], worldPositions[i] = parentRotation, parentPosition
+		} else if !nodes[i].mapping.Position {
+			worldPositions[i] = parentPosition
+		}
+		rotations[i] = quatNormalize(quatMul(quatConjugate(parentRotation), worldRotations[i]))
+		positions[i] = quatRotate(quatConjugate(parentRotation), vec3Sub(worldPositions[i], parentPosition))
+		done[i] = true
+	}
+	for i := range nodes {
+		compute(i)
+	}
+	return rotations, positions
+}
+
+// parentRestWorld 返回父骨骼静止姿态的世界旋转，根骨骼为单位四元数
+func parentRestWorld(bones []*COM3D2.Bone, restWorld []COM3D2.Quaternion, index int) COM3D2.Quaternion {
+	if parent := int(bones[index].ParentIndex); parent >= 0 && parent < len(bones) && parent != index {
+		return restWorld[parent]
+	}
+	return quatIdentity()
+}
+
+// sampleVmdTrack 计算某个 MMD 骨骼在 frame 帧（可以为小数）的位置和旋转增量，插值参数取自后一个关键帧
+func sampleVmdTrack(track []vmdBoneFrame, frame float32) (COM3D2.Vector3, COM3D2.Quaternion) {
+	n := len(track)
+	switch {
+	case n == 0:
+		return COM3D2.Vector3{}, quatIdentity()
+	case frame <= float32(track[0].frame) || n == 1:
+		return track[0].position, quatNormalize(track[0].rotation)
+	case frame >= float32(track[n-1].frame):
+		return track[n-1].position, quatNormalize(track[n-1].rotation)
+	}
+	i := sort.Search(n, func(i int) bool { return float32(track[i].frame) > frame })
+	k0, k1 := track[i-1], track[i]
+	if k1.frame == k0.frame {
+		return k1.position, quatNormalize(k1.rotation)
+	}
+	t := (frame - float32(k0.frame)) / float32(k1.frame-k0.frame)
+	ip := k1.interpolation
+	lerp := func(a, b float32, channel int) float32 {
+		s := vmdBezier(ip[channel], ip[channel+4], ip[channel+8], ip[channel+12], t)
+		return a + (b-a)*s
+	}
+	position := COM3D2.Vector3{
+		X: lerp(k0.position.X, k1.position.X, 0),
+		Y: lerp(k0.position.Y, k1.position.Y, 1),
+		Z: lerp(k0.position.Z, k1.position.Z, 2),
+	}
+	rotation := quatSlerp(quatNormalize(k0.rotation), quatNormalize(k1.rotation), vmdBezier(ip[3], ip[7], ip[11], ip[15], t))
+	return position, rotation
+}
+
+// vmdBezier 计算 VMD 插值曲线在 x = t 处的 y，控制点为 (x1, y1) 和 (x2, y2)，取值范围 0~127
+func vmdBezier(x1, y1, x2, y2 byte, t float32) float32 {
+	if x1 == y1 && x2 == y2 {
+		return t
+	}
+	fx1, fy1, fx2, fy2 := float64(x1)/127, float64(y1)/127, float64(x2)/127, float64(y2)/127
+	bezier := func(s, p1, p2 float64) float64 {
+		u := 1 - s
+		return 3*u*u*s*p1 + 3*u*s*s*p2 + s*s*s
+	}
+	// x(s) 单调递增，二分求解 x(s) = t
+	lo, hi := 0.0, 1.0
+	s := float64(t)
+	for range 32 {
+		s = (lo + hi) / 2
+		if bezier(s, fx1, fx2) < float64(t) {
+			lo = s
+		} else {
+			hi = s
+		}
+	}
+	return float32(bezier(s, fy1, fy2))
+}
+
+// vmdLinearInterpolation 线性插值的参数，控制点为 (20, 20) 和 (107, 107)
+func vmdLinearInterpolation() [64]byte {
+	var row [16]byte
+	for c := 0; c < 4; c++ {
+		row[c], row[c+4], row[c+8], row[c+12] = 20, 20, 107, 107
+	}
+	// 后三行为第一行依次错开一个字节的副本，与 MMD 写出的格式一致
+	var interpolation [64]byte
+	for r := 0; r < 4; r++ {
+		copy(interpolation[r*16:r*16+16-r], row[r:])
+	}
+	return interpolation
+}
+
+// mmdToUnityPosition MMD 中角色面向 -Z，Unity 中面向 +Z，两者都是左手坐标系，绕 Y 轴旋转 180 度
+func mmdToUnityPosition(v COM3D2.Vector3) COM3D2.Vector3 {
+	return COM3D2.Vector3{X: -v.X, Y: v.Y, Z: -v.Z}
+}
+
+// unityToMmdPosition 与 mmdToUnityPosition 相同，该变换是自身的逆
+func unityToMmdPosition(v COM3D2.Vector3) COM3D2.Vector3 {
+	return mmdToUnityPosition(v)
+}
+
+// mmdToUnityRotation 绕 Y 轴旋转 180 度后旋转轴的 X、Z 分量取反
+func mmdToUnityRotation(q COM3D2.Quaternion) COM3D2.Quaternion {
+	return COM3D2.Quaternion{X: -q.X, Y: q.Y, Z: -q.Z, W: q.W}
+}
+
+// unityToMmdRotation 与 mmdToUnityRotation 相同，该变换是自身的逆
+func unityToMmdRotation(q COM3D2.Quaternion) COM3D2.Quaternion {
+	return mmdToUnityRotation(q)
+}
+
+// parseVmd 解析 VMD 文件的头部和骨骼关键帧，表情、相机等其余部分会被忽略
+func parseVmd(r io.Reader) (*vmdMotion, error) {
+	header := make([]byte, 30)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	nameSize := 0
+	switch {
+	case bytes.HasPrefix(header, []byte(vmdSignature)):
+		nameSize = 20
+	case bytes.HasPrefix(header, []byte(vmdLegacySignature)):
+		nameSize = 10
+	default:
+		return nil, fmt.Errorf("invalid vmd signature %q", strings.TrimRight(string(header), "\x00"))
+	}
+	name := make([]byte, nameSize)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, fmt.Errorf("failed to read model name: %w", err)
+	}
+	motion := &vmdMotion{modelName: decodeShiftJIS(name)}
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("failed to read bone keyframe count: %w", err)
+	}
+	buf := make([]byte, vmdBoneFrameSize)
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("failed to read bone keyframe %d of %d: %w", i, count, err)
+		}
+		frame := vmdBoneFrame{
+			name:  decodeShiftJIS(buf[:vmdBoneNameSize]),
+			frame: binary.LittleEndian.Uint32(buf[15:19]),
+		}
+		f := func(offset int) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(buf[offset : offset+4]))
+		}
+		frame.position = COM3D2.Vector3{X: f(19), Y: f(23), Z: f(27)}
+		frame.rotation = COM3D2.Quaternion{X: f(31), Y: f(35), Z: f(39), W: f(43)}
+		copy(frame.interpolation[:], buf[47:])
+		motion.boneFrames = append(motion.boneFrames, frame)
+	}
+	return motion, nil
+}
+
+// writeVmd 写入 VMD 文件，表情、相机、照明和阴影的关键帧数量为 0
+func writeVmd(w io.Writer, motion *vmdMotion) error {
+	header := make([]byte, 30)
+	copy(header, vmdSignature)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	name, err := encodeShiftJIS(motion.modelName, 20, true)
+	if err != nil {
+		return fmt.Errorf("invalid model name: %w", err)
+	}
+	if _, err := w.Write(name); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(motion.boneFrames))); err != nil {
+		return err
+	}
+
+	buf := make([]byte, vmdBoneFrameSize)
+	for _, frame := range motion.boneFrames {
+		boneName, err := encodeShiftJIS(frame.name, vmdBoneNameSize, false)
+		if err != nil {
+			return fmt.Errorf("invalid bone name %q: %w", frame.name, err)
+		}
+		copy(buf, boneName)
+		binary.LittleEndian.PutUint32(buf[15:19], frame.frame)
+		for i, v := range []float32{
+			frame.position.X, frame.position.Y, frame.position.Z,
+			frame.rotation.X, frame.rotation.Y, frame.rotation.Z, frame.rotation.W,
+		} {
+			binary.LittleEndian.PutUint32(buf[19+i*4:23+i*4], math.Float32bits(v))
+		}
+		copy(buf[47:], frame.interpolation[:])
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	// 表情、相机、照明、阴影
+	for range 4 {
+		if err := binary.Write(w, binary.LittleEndian, uint32(0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeShiftJIS 解码以 0 结尾的 Shift-JIS 字符串，解码失败时按原样返回
+func decodeShiftJIS(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(decoded)
+}
+
+// encodeShiftJIS 编码为固定长度、以 0 填充的 Shift-JIS 字符串
+// 超出长度时，truncate 为 true 则按字符截断，否则返回错误
+func encodeShiftJIS(s string, size int, truncate bool) ([]byte, error) {
+	encoder := japanese.ShiftJIS.NewEncoder()
+	result := make([]byte, 0, size)
+	for _, r := range s {
+		encoded, err := encoder.Bytes([]byte(string(r)))
+		if err != nil {
+			return nil, fmt.Errorf("character %q cannot be encoded as Shift-JIS", r)
+		}
+		if len(result)+len(encoded) > size {
+			if truncate {
+				break
+			}
+			return nil, fmt.Errorf("%q is longer than %d bytes in Shift-JIS", s, size)
+		}
+		result = append(result, encoded...)
+	}
+	return append(result, make([]byte, size-len(result))...), nil
+}
+
+// defaultVmdBoneMap 默认的 MMD 标准骨骼映射表，父骨骼在前
+// センター、腰等没有对应 COM3D2 骨骼的 MMD 骨骼只参与层级计算，捩骨骼和 IK 骨骼不包含在内
+func defaultVmdBoneMap() []VmdBoneMapping {
+	boneMap := []VmdBoneMapping{
+		{MMD: "全ての親"},
+		{MMD: "センター", MMDParent: "全ての親"},
+		{MMD: "グルーブ", MMDParent: "センター", COM3D2: "Bip01", Position: true},
+		{MMD: "腰", MMDParent: "グルーブ"},
+		{MMD: "上半身", MMDParent: "腰", COM3D2: "Bip01 Spine"},
+		{MMD: "上半身2", MMDParent: "上半身", COM3D2: "Bip01 Spine1"},
+		{MMD: "首", MMDParent: "上半身2", COM3D2: "Bip01 Neck"},
+		{MMD: "頭", MMDParent: "首", COM3D2: "Bip01 Head"},
+		{MMD: "下半身", MMDParent: "腰", COM3D2: "Bip01 Pelvis"},
+	}
+	fingers := []struct{ mmd, com string }{
+		{"親指", "0"}, {"人指", "1"}, {"中指", "2"}, {"薬指", "3"}, {"小指", "4"},
+	}
+	for _, side := range []struct{ mmd, com string }{{"左", "L"}, {"右", "R"}} {
+		bip := "Bip01 " + side.com + " "
+		boneMap = append(boneMap,
+			VmdBoneMapping{MMD: side.mmd + "肩", MMDParent: "上半身2", COM3D2: bip + "Clavicle"},
+			VmdBoneMapping{MMD: side.mmd + "腕", MMDParent: side.mmd + "肩", COM3D2: bip + "UpperArm"},
+			VmdBoneMapping{MMD: side.mmd + "ひじ", MMDParent: side.mmd + "腕", COM3D2: bip + "Forearm"},
+			VmdBoneMapping{MMD: side.mmd + "手首", MMDParent: side.mmd + "ひじ", COM3D2: bip + "Hand"},
+			VmdBoneMapping{MMD: side.mmd + "足", MMDParent: "下半身", COM3D2: bip + "Thigh"},
+			VmdBoneMapping{MMD: side.mmd + "ひざ", MMDParent: side.mmd + "足", COM3D2: bip + "Calf"},
+			VmdBoneMapping{MMD: side.mmd + "足首", MMDParent: side.mmd + "ひざ", COM3D2: bip + "Foot"},
+		)
+		for _, finger := range fingers {
+			// MMD 的手指骨骼名使用全角数字，拇指为 １、２，其余手指为 １、２、３
+			segments := []string{"１", "２", "３"}
+			if finger.com == "0" {
+				segments = segments[:2]
+			}
+			parent := side.mmd + "手首"
+			for i, segment := range segments {
+				name := side.mmd + finger.mmd + segment
+				com := bip + "Finger" + finger.com
+				if i > 0 {
+					com += fmt.Sprint(i)
+				}
+				boneMap = append(boneMap, VmdBoneMapping{MMD: name, MMDParent: parent, COM3D2: com})
+				parent = name
+			}
+		}
+	}
+	return boneMap
+}
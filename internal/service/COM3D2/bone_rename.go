@@ -0,0 +1,318 @@
+package COM3D2
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// BoneRenameChange 重命名骨骼时受影响的单个字段
+type BoneRenameChange struct {
+	Path     string `json:"Path"`     // 文件路径
+	FileType string `json:"FileType"` // 文件类型 model/anm/phy/col
+	Field    string `json:"Field"`    // 字段位置，例如 Bones[3].Name
+	OldValue string `json:"OldValue"` // 原值
+	NewValue string `json:"NewValue"` // 新值
+}
+
+// BoneRenameResult 骨骼重命名结果，DryRun 时为预览
+type BoneRenameResult struct {
+	Changes      []BoneRenameChange `json:"Changes"`      // 所有受影响的字段
+	ChangedFiles []string           `json:"ChangedFiles"` // 有改动的文件
+}
+
+// RenameBones 按照 mapping（旧名 -> 新名）在多个文件中一致地重命名骨骼
+// 支持 .model、.anm、.phy、.col 以及它们的 .json 格式，会同时修改：
+// .model 的 Bones、BoneNames、RootBoneName 和 SkinThickness 中的骨骼名
+// .anm 的 BonePath 中的每一段
+// .phy 的 RootName 和各 Partial* 列表中的 BoneName
+// .col 中碰撞体的 ParentName
+// 所有文件都读取并重命名成功后才会写出，任一文件出错时不修改任何文件
+// dryRun 为 true 时只返回预览，不写出文件；否则覆盖原文件
+func (m *CommonService) RenameBones(paths []string, mapping map[string]string, dryRun bool) (result BoneRenameResult, err error) {
+	result.Changes = []BoneRenameChange{}
+	result.ChangedFiles = []string{}
+
+	newNames := make(map[string]string, len(mapping))
+	for oldName, newName := range mapping {
+		if oldName == "" || newName == "" {
+			return result, fmt.Errorf("bone rename mapping contains empty name: %q -> %q", oldName, newName)
+		}
+		if other, ok := newNames[newName]; ok {
+			return result, fmt.Errorf("bone rename mapping renames both %q and %q to %q", other, oldName, newName)
+		}
+		newNames[newName] = oldName
+	}
+
+	// 先在内存中处理所有文件，全部成功后再写出；同一文件只处理一次
+	var writes []boneRenameWrite
+	seenPaths := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return result, fmt.Errorf("failed to resolve path %s: %w", path, err)
+		}
+		if _, ok := seenPaths[absPath]; ok {
+			continue
+		}
+		seenPaths[absPath] = struct{}{}
+
+		fileInfo, err := m.FileTypeDetermine(path, false)
+		if err != nil {
+			return result, fmt.Errorf("failed to determine file type of %s: %w", path, err)
+		}
+
+		var changes []BoneRenameChange
+		var write func(path string) error
+		switch fileInfo.FileType {
+		case "model":
+			changes, write, err = renameBonesInModelFile(path, mapping)
+		case "anm":
+			changes, write, err = renameBonesInAnmFile(path, mapping)
+		case "phy":
+			changes, write, err = renameBonesInPhyFile(path, mapping)
+		case "col":
+			changes, write, err = renameBonesInColFile(path, mapping)
+		default:
+			return result, fmt.Errorf("unsupported file type for bone rename: %s (%s)", fileInfo.FileType, path)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		for i := range changes {
+			changes[i].Path = path
+			changes[i].FileType = fileInfo.FileType
+		}
+		if len(changes) > 0 {
+			result.Changes = append(result.Changes, changes...)
+			result.ChangedFiles = append(result.ChangedFiles, path)
+			writes = append(writes, boneRenameWrite{path: path, write: write})
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := commitBoneRenameWrites(writes); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// boneRenameWrite 等待写出的文件，write 将修改后的数据写入给定路径
+type boneRenameWrite struct {
+	path  string
+	write func(path string) error
+}
+
+// commitBoneRenameWrites 先把所有文件写到同目录下的临时文件，全部成功后再逐个替换原文件
+// 替换前原文件会被改名备份，任一文件替换失败时恢复所有已替换的文件，全部成功后删除备份
+// 临时文件名保留原文件名作为后缀，因此仍按扩展名判断是否为 .json 格式
+func commitBoneRenameWrites(writes []boneRenameWrite) error {
+	tempPaths := make([]string, len(writes))
+	backupPaths := make([]string, len(writes))
+	removeAll := func(paths []string) {
+		for _, path := range paths {
+			if path != "" {
+				os.Remove(path)
+			}
+		}
+	}
+	for i, w := range writes {
+		dir, base := filepath.Dir(w.path), filepath.Base(w.path)
+		tempPaths[i] = filepath.Join(dir, ".bone_rename_"+base)
+		backupPaths[i] = filepath.Join(dir, ".bone_rename_backup_"+base)
+		if err := w.write(tempPaths[i]); err != nil {
+			removeAll(tempPaths[:i+1])
+			return fmt.Errorf("failed to write %s: %w", w.path, err)
+		}
+	}
+
+	for i, w := range writes {
+		restored := i // 需要从备份恢复的文件数
+		err := os.Rename(w.path, backupPaths[i])
+		if err == nil {
+			if err = os.Rename(tempPaths[i], w.path); err != nil {
+				restored = i + 1 // 当前文件的备份已经生成，与之前的文件一起恢复
+			}
+		}
+		if err == nil {
+			continue
+		}
+		replaceErr := fmt.Errorf("failed to replace %s: %w", w.path, err)
+		for j := 0; j < restored; j++ {
+			if restoreErr := os.Rename(backupPaths[j], writes[j].path); restoreErr != nil {
+				return fmt.Errorf("%w, and failed to restore %s from %s: %v", replaceErr, writes[j].path, backupPaths[j], restoreErr)
+			}
+		}
+		removeAll(tempPaths)
+		return replaceErr
+	}
+	removeAll(backupPaths)
+	return nil
+}
+
+// boneRenamer 记录重命名过程中的字段变化
+type boneRenamer struct {
+	mapping map[string]string
+	changes []BoneRenameChange
+}
+
+// rename 如果 name 在映射表中，则记录变化并返回新名，否则原样返回
+func (r *boneRenamer) rename(field string, name string) string {
+	newName, ok := r.mapping[name]
+	if !ok || newName == name {
+		return name
+	}
+	r.changes = append(r.changes, BoneRenameChange{Field: field, OldValue: name, NewValue: newName})
+	return newName
+}
+
+// renamePath 对 / 分隔的骨骼路径逐段重命名
+func (r *boneRenamer) renamePath(field string, path string) string {
+	segments := strings.Split(path, "/")
+	changed := false
+	for i, segment := range segments {
+		if newName, ok := r.mapping[segment]; ok && newName != segment {
+			segments[i] = newName
+			changed = true
+		}
+	}
+	if !changed {
+		return path
+	}
+	newPath := strings.Join(segments, "/")
+	r.changes = append(r.changes, BoneRenameChange{Field: field, OldValue: path, NewValue: newPath})
+	return newPath
+}
+
+// renameBonesInModelFile 在内存中重命名，返回的 write 用于写出修改后的数据
+func renameBonesInModelFile(path string, mapping map[string]string) ([]BoneRenameChange, func(path string) error, error) {
+	modelService := &ModelService{}
+	modelData, err := modelService.ReadModelFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 检查新名称是否与未被重命名的骨骼（包括蒙皮骨骼 BoneNames）冲突
+	existing := make(map[string]struct{}, len(modelData.Bones)+len(modelData.BoneNames))
+	for _, bone := range modelData.Bones {
+		if _, renamed := mapping[bone.Name]; !renamed {
+			existing[bone.Name] = struct{}{}
+		}
+	}
+	for _, name := range modelData.BoneNames {
+		if _, renamed := mapping[name]; !renamed {
+			existing[name] = struct{}{}
+		}
+	}
+	for oldName, newName := range mapping {
+		if _, conflict := existing[newName]; conflict && oldName != newName {
+			return nil, nil, fmt.Errorf("cannot rename bone %q to %q in %s: a bone with that name already exists", oldName, newName, path)
+		}
+	}
+
+	r := &boneRenamer{mapping: mapping}
+	modelData.RootBoneName = r.rename("RootBoneName", modelData.RootBoneName)
+	for i := range modelData.Bones {
+		modelData.Bones[i].Name = r.rename(fmt.Sprintf("Bones[%d].Name", i), modelData.Bones[i].Name)
+	}
+	for i := range modelData.BoneNames {
+		modelData.BoneNames[i] = r.rename(fmt.Sprintf("BoneNames[%d]", i), modelData.BoneNames[i])
+	}
+	if modelData.SkinThickness != nil {
+		for key, group := range modelData.SkinThickness.Groups {
+			if group == nil {
+				continue
+			}
+			group.StartBoneName = r.rename(fmt.Sprintf("SkinThickness.Groups[%s].StartBoneName", key), group.StartBoneName)
+			group.EndBoneName = r.rename(fmt.Sprintf("SkinThickness.Groups[%s].EndBoneName", key), group.EndBoneName)
+			for j, point := range group.Points {
+				if point != nil {
+					point.TargetBoneName = r.rename(fmt.Sprintf("SkinThickness.Groups[%s].Points[%d].TargetBoneName", key, j), point.TargetBoneName)
+				}
+			}
+		}
+	}
+
+	return r.changes, func(path string) error {
+		return modelService.WriteModelFile(path, modelData)
+	}, nil
+}
+
+// renameBonesInAnmFile 在内存中重命名，返回的 write 用于写出修改后的数据
+func renameBonesInAnmFile(path string, mapping map[string]string) ([]BoneRenameChange, func(path string) error, error) {
+	anmService := &AnmService{}
+	anmData, err := anmService.ReadAnmFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &boneRenamer{mapping: mapping}
+	for i := range anmData.BoneCurves {
+		anmData.BoneCurves[i].BonePath = r.renamePath(fmt.Sprintf("BoneCurves[%d].BonePath", i), anmData.BoneCurves[i].BonePath)
+	}
+
+	return r.changes, func(path string) error {
+		return anmService.WriteAnmFile(path, anmData)
+	}, nil
+}
+
+// renameBonesInPhyFile 在内存中重命名，返回的 write 用于写出修改后的数据
+func renameBonesInPhyFile(path string, mapping map[string]string) ([]BoneRenameChange, func(path string) error, error) {
+	phyService := &PhyService{}
+	phyData, err := phyService.ReadPhyFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &boneRenamer{mapping: mapping}
+	phyData.RootName = r.rename("RootName", phyData.RootName)
+	renameBoneValues := func(field string, values []COM3D2.BoneValue) {
+		for i := range values {
+			values[i].BoneName = r.rename(fmt.Sprintf("%s[%d].BoneName", field, i), values[i].BoneName)
+		}
+	}
+	renameBoneValues("PartialDamping", phyData.PartialDamping)
+	renameBoneValues("PartialElasticity", phyData.PartialElasticity)
+	renameBoneValues("PartialStiffness", phyData.PartialStiffness)
+	renameBoneValues("PartialInert", phyData.PartialInert)
+	renameBoneValues("PartialRadius", phyData.PartialRadius)
+
+	return r.changes, func(path string) error {
+		return phyService.WritePhyFile(path, phyData)
+	}, nil
+}
+
+// renameBonesInColFile 在内存中重命名，返回的 write 用于写出修改后的数据
+func renameBonesInColFile(path string, mapping map[string]string) ([]BoneRenameChange, func(path string) error, error) {
+	colService := &ColService{}
+	colData, err := colService.ReadColFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &boneRenamer{mapping: mapping}
+	for i, collider := range colData.Colliders {
+		var base *COM3D2.DynamicBoneColliderBase
+		switch c := collider.(type) {
+		case *COM3D2.DynamicBoneCollider:
+			base = c.Base
+		case *COM3D2.DynamicBoneMuneCollider:
+			base = c.Base
+		case *COM3D2.DynamicBonePlaneCollider:
+			base = c.Base
+		}
+		if base != nil {
+			base.ParentName = r.rename(fmt.Sprintf("Colliders[%d].ParentName", i), base.ParentName)
+		}
+	}
+
+	return r.changes, func(path string) error {
+		return colService.WriteColFile(path, colData)
+	}, nil
+}
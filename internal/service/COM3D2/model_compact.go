@@ -0,0 +1,212 @@
+package COM3D2
+
+import (
+	"fmt"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// ModelCompactOptions 模型精简选项
+type ModelCompactOptions struct {
+	DryRun         bool     `json:"DryRun"`         // 为 true 时只返回将要移除的内容，不写出文件
+	PruneHierarchy bool     `json:"PruneHierarchy"` // 是否同时移除 Bones 层级中没有被引用的骨骼（phy、col 等文件可能通过名称引用骨骼，请谨慎使用）
+	KeepBones      []string `json:"KeepBones"`      // 无论是否被引用都保留的骨骼名
+}
+
+// ModelCompactResult 模型精简结果，DryRun 时为将要移除的内容
+type ModelCompactResult struct {
+	RemovedSkinBones []string `json:"RemovedSkinBones"` // 从 BoneNames 和 BindPoses 中移除的蒙皮骨骼
+	RemovedBones     []string `json:"RemovedBones"`     // 从 Bones 层级中移除的骨骼
+	RemovedSubMeshes []int    `json:"RemovedSubMeshes"` // 移除的空子网格索引（原索引）
+	RemovedMaterials []string `json:"RemovedMaterials"` // 移除的材质名
+}
+
+// CompactModel 读取 .model 文件，移除没有被引用的骨骼、空子网格和多余的材质后写入 outputPath，可以为相同路径
+// 蒙皮骨骼指 BoneNames 中的骨骼，没有任何顶点权重引用时会被移除，并重新映射 BoneWeights 中的索引
+// 子网格与材质按下标一一对应，空子网格会连同其材质一起移除，超出子网格数量的材质也会被移除
+func (m *ModelService) CompactModel(inputPath string, outputPath string, options ModelCompactOptions) (result ModelCompactResult, err error) {
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	result = compactModel(modelData, options)
+	if options.DryRun {
+		return result, nil
+	}
+
+	return result, m.WriteModelFile(outputPath, modelData)
+}
+
+// compactModel 精简模型，DryRun 时不修改模型
+func compactModel(modelData *COM3D2.Model, options ModelCompactOptions) (result ModelCompactResult) {
+	result.RemovedSkinBones = []string{}
+	result.RemovedBones = []string{}
+	result.RemovedSubMeshes = []int{}
+	result.RemovedMaterials = []string{}
+
+	// 蒙皮骨骼
+	usedSkin := make([]bool, len(modelData.BoneNames))
+	for _, bw := range modelData.BoneWeights {
+		for _, inf := range getBoneInfluences(bw) {
+			if inf.Weight > 0 && inf.Index < len(usedSkin) {
+				usedSkin[inf.Index] = true
+			}
+		}
+	}
+	keepNames := make(map[string]struct{}, len(options.KeepBones))
+	for _, name := range options.KeepBones {
+		keepNames[name] = struct{}{}
+	}
+	for i, name := range modelData.BoneNames {
+		if _, keep := keepNames[name]; keep {
+			usedSkin[i] = true
+		}
+		if !usedSkin[i] {
+			result.RemovedSkinBones = append(result.RemovedSkinBones, name)
+		}
+	}
+
+	// 子网格和材质
+	keepSubMesh := make([]bool, len(modelData.SubMeshes))
+	for i, subMesh := range modelData.SubMeshes {
+		keepSubMesh[i] = len(subMesh) > 0
+		if !keepSubMesh[i] {
+			result.RemovedSubMeshes = append(result.RemovedSubMeshes, i)
+		}
+	}
+	keepMaterial := make([]bool, len(modelData.Materials))
+	for i, material := range modelData.Materials {
+		keepMaterial[i] = i < len(keepSubMesh) && keepSubMesh[i]
+		if !keepMaterial[i] {
+			result.RemovedMaterials = append(result.RemovedMaterials, material.Name)
+		}
+	}
+
+	// 层级骨骼，保留蒙皮骨骼、根骨骼、指定骨骼、SkinThickness 引用的骨骼以及它们的所有父骨骼
+	var keepBone []bool
+	if options.PruneHierarchy {
+		needed := make(map[string]struct{})
+		for i, name := range modelData.BoneNames {
+			if usedSkin[i] {
+				needed[name] = struct{}{}
+			}
+		}
+		for name := range keepNames {
+			needed[name] = struct{}{}
+		}
+		needed[modelData.RootBoneName] = struct{}{}
+		for _, name := range skinThicknessBoneNames(modelData.SkinThickness) {
+			needed[name] = struct{}{}
+		}
+
+		keepBone = make([]bool, len(modelData.Bones))
+		for i, bone := range modelData.Bones {
+			if _, ok := needed[bone.Name]; !ok {
+				continue
+			}
+			for current, steps := i, 0; current >= 0 && current < len(modelData.Bones) && !keepBone[current] && steps <= len(modelData.Bones); steps++ {
+				keepBone[current] = true
+				current = int(modelData.Bones[current].ParentIndex)
+			}
+		}
+		for i, bone := range modelData.Bones {
+			if !keepBone[i] {
+				result.RemovedBones = append(result.RemovedBones, bone.Name)
+			}
+		}
+	}
+
+	if options.DryRun {
+		return result
+	}
+
+	if len(result.RemovedSkinBones) > 0 {
+		skinRemap := buildIndexRemap(usedSkin)
+		for i := range modelData.BoneWeights {
+			influences := getBoneInfluences(modelData.BoneWeights[i])
+			for j := range influences {
+				if influences[j].Weight > 0 && influences[j].Index < len(skinRemap) {
+					influences[j].Index = skinRemap[influences[j].Index]
+				} else {
+					influences[j] = boneInfluence{}
+				}
+			}
+			setBoneInfluences(&modelData.BoneWeights[i], influences[:])
+		}
+		modelData.BoneNames = filterByMask(modelData.BoneNames, usedSkin)
+		if len(modelData.BindPoses) == len(usedSkin) {
+			modelData.BindPoses = filterByMask(modelData.BindPoses, usedSkin)
+		}
+		modelData.BoneCount = int32(len(modelData.BoneNames))
+	}
+
+	if len(result.RemovedSubMeshes) > 0 {
+		modelData.SubMeshes = filterByMask(modelData.SubMeshes, keepSubMesh)
+		modelData.SubMeshCount = int32(len(modelData.SubMeshes))
+	}
+	if len(result.RemovedMaterials) > 0 {
+		modelData.Materials = filterByMask(modelData.Materials, keepMaterial)
+	}
+
+	if len(result.RemovedBones) > 0 {
+		boneRemap := buildIndexRemap(keepBone)
+		modelData.Bones = filterByMask(modelData.Bones, keepBone)
+		for i := range modelData.Bones {
+			parent := int(modelData.Bones[i].ParentIndex)
+			if parent >= 0 && parent < len(boneRemap) {
+				modelData.Bones[i].ParentIndex = int32(boneRemap[parent])
+			} else {
+				modelData.Bones[i].ParentIndex = -1
+			}
+		}
+	}
+
+	return result
+}
+
+// skinThicknessBoneNames 返回 SkinThickness 中引用的所有骨骼名
+func skinThicknessBoneNames(skinThickness *COM3D2.SkinThickness) []string {
+	if skinThickness == nil {
+		return nil
+	}
+	var names []string
+	for _, group := range skinThickness.Groups {
+		if group == nil {
+			continue
+		}
+		names = append(names, group.StartBoneName, group.EndBoneName)
+		for _, point := range group.Points {
+			if point != nil {
+				names = append(names, point.TargetBoneName)
+			}
+		}
+	}
+	return names
+}
+
+// buildIndexRemap 根据保留标记生成旧索引到新索引的映射，被移除的索引映射为 -1
+func buildIndexRemap(keep []bool) []int {
+	remap := make([]int, len(keep))
+	next := 0
+	for i, k := range keep {
+		if k {
+			remap[i] = next
+			next++
+		} else {
+			remap[i] = -1
+		}
+	}
+	return remap
+}
+
+// filterByMask 返回 keep 中标记为 true 的元素组成的新切片
+func filterByMask[T any](items []T, keep []bool) []T {
+	filtered := make([]T, 0, len(items))
+	for i, item := range items {
+		if i < len(keep) && keep[i] {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
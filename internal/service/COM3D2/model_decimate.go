@@ -0,0 +1,554 @@
+package COM3D2
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// DecimateOptions 网格减面选项
+type DecimateOptions struct {
+	TargetRatio    float32 `json:"TargetRatio"`    // 目标三角形数与原三角形数之比，范围 (0, 1)
+	SubMeshIndex   int     `json:"SubMeshIndex"`   // 只处理该子网格，-1 表示整个模型
+	LockBoundaries bool    `json:"LockBoundaries"` // 是否保留开放边界（如衣服边缘、袖口）上的顶点
+}
+
+// DecimateResult 网格减面结果
+type DecimateResult struct {
+	OriginalTriangles int `json:"OriginalTriangles"` // 原三角形数（处理范围内）
+	FinalTriangles    int `json:"FinalTriangles"`    // 减面后的三角形数（处理范围内）
+	OriginalVertices  int `json:"OriginalVertices"`  // 原顶点数
+	FinalVertices     int `json:"FinalVertices"`     // 减面后的顶点数
+	LockedVertices    int `json:"LockedVertices"`    // 因位于 UV 接缝、材质边界或开放边界而不能移除的顶点数
+}
+
+// DecimateModel 使用二次误差度量对模型减面，写入 outputPath
+// 采用半边折叠，被移除的顶点合并到相邻顶点上，保留下来的顶点的 UV、骨骼权重和形态键偏移保持原值
+// UV 接缝和材质边界上的顶点不会被移除，折叠代价中包含骨骼权重和形态键偏移的差异，以尽量保持蒙皮和形态键效果
+func (m *ModelService) DecimateModel(inputPath string, outputPath string, options DecimateOptions) (result DecimateResult, err error) {
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = decimateModel(modelData, options)
+		return err
+	})
+	return result, err
+}
+
+// quadric 对称 4x4 矩阵的上三角部分，表示到一组平面的距离平方和
+type quadric [10]float64
+
+// planeQuadric 根据平面 ax + by + cz + d = 0 构造二次误差矩阵，并乘以权重
+func planeQuadric(a, b, c, d, weight float64) quadric {
+	return quadric{
+		a * a * weight, a * b * weight, a * c * weight, a * d * weight,
+		b * b * weight, b * c * weight, b * d * weight,
+		c * c * weight, c * d * weight,
+		d * d * weight,
+	}
+}
+
+// add 累加二次误差矩阵
+func (q *quadric) add(o quadric) {
+	for i := range q {
+		q[i] += o[i]
+	}
+}
+
+// evaluate 计算点 p 的误差
+func (q *quadric) evaluate(p COM3D2.Vector3) float64 {
+	x, y, z := float64(p.X), float64(p.Y), float64(p.Z)
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// collapseCandidate 将顶点 from 折叠到 to 的候选操作
+type collapseCandidate struct {
+	cost      float64
+	from, to  int
+	fromStamp int
+	toStamp   int
+}
+
+// collapseQueue 按代价排序的最小堆
+type collapseQueue []collapseCandidate
+
+func (q collapseQueue) Len() int           { return len(q) }
+func (q collapseQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q collapseQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *collapseQueue) Push(x any)        { *q = append(*q, x.(collapseCandidate)) }
+func (q *collapseQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// morphDelta 顶点在某个形态键中的偏移
+type morphDelta struct {
+	morph int
+	delta COM3D2.Vector3
+}
+
+// decimator 减面过程中的状态
+type decimator struct {
+	model         *COM3D2.Model
+	positions     []COM3D2.Vector3
+	triangles     [][3]int
+	triangleAlive []bool
+	vertexTris    [][]int
+	quadrics      []quadric
+	locked        []bool
+	removed       []bool
+	stamps        []int
+	collapsedInto []int
+	morphDeltas   [][]morphDelta
+	queue         collapseQueue
+}
+
+// decimateModel 就地对模型减面
+func decimateModel(modelData *COM3D2.Model, options DecimateOptions) (result DecimateResult, err error) {
+	if options.TargetRatio <= 0 || options.TargetRatio >= 1 {
+		return result, fmt.Errorf("target ratio must be in (0, 1), got %v", options.TargetRatio)
+	}
+	if options.SubMeshIndex >= len(modelData.SubMeshes) || options.SubMeshIndex < -1 {
+		return result, fmt.Errorf("submesh %d out of range [0, %d)", options.SubMeshIndex, len(modelData.SubMeshes))
+	}
+	// 只处理一个子网格时也会读取其他子网格来确定材质边界，因此检查所有子网格的索引
+	if _, err := collectTriangles(modelData, -1); err != nil {
+		return result, err
+	}
+	d := newDecimator(modelData, options)
+	result.OriginalVertices = len(modelData.Vertices)
+	for _, locked := range d.locked {
+		if locked {
+			result.LockedVertices++
+		}
+	}
+
+	alive := 0
+	for t := range d.triangles {
+		if d.triangleAlive[t] && d.inScope(t, options.SubMeshIndex) {
+			alive++
+		}
+	}
+	result.OriginalTriangles = alive
+	target := int(math.Ceil(float64(alive) * float64(options.TargetRatio)))
+
+	for alive > target && d.queue.Len() > 0 {
+		c := heap.Pop(&d.queue).(collapseCandidate)
+		if d.removed[c.from] || d.removed[c.to] || d.stamps[c.from] != c.fromStamp || d.stamps[c.to] != c.toStamp {
+			continue
+		}
+		if !d.canCollapse(c.from, c.to) {
+			continue
+		}
+		alive -= d.collapse(c.from, c.to)
+	}
+	result.FinalTriangles = alive
+
+	d.apply()
+	result.FinalVertices = len(modelData.Vertices)
+	return result, nil
+}
+
+// newDecimator 建立邻接关系、二次误差矩阵和初始的折叠候选
+func newDecimator(modelData *COM3D2.Model, options DecimateOptions) *decimator {
+	vertexCount := len(modelData.Vertices)
+	d := &decimator{
+		model:         modelData,
+		positions:     vertexPositions(modelData),
+		vertexTris:    make([][]int, vertexCount),
+		quadrics:      make([]quadric, vertexCount),
+		locked:        make([]bool, vertexCount),
+		removed:       make([]bool, vertexCount),
+		stamps:        make([]int, vertexCount),
+		collapsedInto: make([]int, vertexCount),
+		morphDeltas:   make([][]morphDelta, vertexCount),
+	}
+	for i := range d.collapsedInto {
+		d.collapsedInto[i] = -1
+	}
+
+	// 记录每个顶点所在的子网格，用于判断材质边界
+	vertexSubMesh := make([]int, vertexCount)
+	for i := range vertexSubMesh {
+		vertexSubMesh[i] = -1
+	}
+	for s, subMesh := range modelData.SubMeshes {
+		for t := 0; t+2 < len(subMesh); t += 3 {
+			tri := [3]int{int(subMesh[t]), int(subMesh[t+1]), int(subMesh[t+2])}
+			index := len(d.triangles)
+			d.triangles = append(d.triangles, tri)
+			d.triangleAlive = append(d.triangleAlive, tri[0] != tri[1] && tri[1] != tri[2] && tri[0] != tri[2])
+			for _, v := range tri {
+				d.vertexTris[v] = append(d.vertexTris[v], index)
+				if vertexSubMesh[v] == -1 {
+					vertexSubMesh[v] = s
+				} else if vertexSubMesh[v] != s {
+					d.locked[v] = true // 材质边界
+				}
+			}
+			if !d.triangleAlive[index] {
+				continue
+			}
+			p0, p1, p2 := d.positions[tri[0]], d.positions[tri[1]], d.positions[tri[2]]
+			n := vec3Cross(vec3Sub(p1, p0), vec3Sub(p2, p0))
+			area := float64(vec3Length(n)) / 2
+			if area == 0 {
+				continue
+			}
+			n = vec3Normalize(n)
+			q := planeQuadric(float64(n.X), float64(n.Y), float64(n.Z), -float64(vec3Dot(n, p0)), area)
+			for _, v := range tri {
+				d.quadrics[v].add(q)
+			}
+		}
+	}
+	if options.SubMeshIndex >= 0 {
+		for v, s := range vertexSubMesh {
+			if s != options.SubMeshIndex {
+				d.locked[v] = true
+			}
+		}
+	}
+
+	// UV 接缝：位置相同的多个顶点
+	samePosition := make(map[COM3D2.Vector3][]int)
+	for v, p := range d.positions {
+		samePosition[p] = append(samePosition[p], v)
+	}
+	for _, group := range samePosition {
+		if len(group) > 1 {
+			for _, v := range group {
+				d.locked[v] = true
+			}
+		}
+	}
+
+	// 开放边界：只属于一个三角形的边
+	if options.LockBoundaries {
+		edgeCount := make(map[[2]int]int)
+		for t, tri := range d.triangles {
+			if !d.triangleAlive[t] {
+				continue
+			}
+			for i := 0; i < 3; i++ {
+				a, b := tri[i], tri[(i+1)%3]
+				edgeCount[[2]int{min(a, b), max(a, b)}]++
+			}
+		}
+		for edge, count := range edgeCount {
+			if count == 1 {
+				d.locked[edge[0]] = true
+				d.locked[edge[1]] = true
+			}
+		}
+	}
+
+	for m, morph := range modelData.MorphData {
+		for i, vertexIndex := range morph.Indices {
+			if v := int(vertexIndex); v >= 0 && v < vertexCount && i < len(morph.Vertex) {
+				d.morphDeltas[v] = append(d.morphDeltas[v], morphDelta{morph: m, delta: morph.Vertex[i]})
+			}
+		}
+	}
+
+	for v := range d.positions {
+		d.pushCandidates(v)
+	}
+	return d
+}
+
+// inScope 判断三角形是否在处理范围内
+func (d *decimator) inScope(t int, subMeshIndex int) bool {
+	if subMeshIndex < 0 {
+		return true
+	}
+	offset := 0
+	for s, subMesh := range d.model.SubMeshes {
+		count := len(subMesh) / 3
+		if t < offset+count {
+			return s == subMeshIndex
+		}
+		offset += count
+	}
+	return false
+}
+
+// neighbors 返回与顶点相邻的顶点
+func (d *decimator) neighbors(v int) []int {
+	seen := make(map[int]struct{})
+	var result []int
+	for _, t := range d.vertexTris[v] {
+		if !d.triangleAlive[t] {
+			continue
+		}
+		for _, n := range d.triangles[t] {
+			if _, ok := seen[n]; !ok && n != v {
+				seen[n] = struct{}{}
+				result = append(result, n)
+			}
+		}
+	}
+	return result
+}
+
+// pushCandidates 将与顶点 v 相关的折叠候选加入队列
+func (d *decimator) pushCandidates(v int) {
+	for _, n := range d.neighbors(v) {
+		if !d.locked[v] {
+			heap.Push(&d.queue, collapseCandidate{cost: d.cost(v, n), from: v, to: n, fromStamp: d.stamps[v], toStamp: d.stamps[n]})
+		}
+		if !d.locked[n] {
+			heap.Push(&d.queue, collapseCandidate{cost: d.cost(n, v), from: n, to: v, fromStamp: d.stamps[n], toStamp: d.stamps[v]})
+		}
+	}
+}
+
+// cost 计算将 from 折叠到 to 的代价：几何误差 + 骨骼权重差异 + 形态键偏移差异
+func (d *decimator) cost(from, to int) float64 {
+	q := d.quadrics[from]
+	q.add(d.quadrics[to])
+	cost := q.evaluate(d.positions[to])
+
+	edge := vec3Sub(d.positions[from], d.positions[to])
+	edgeLengthSquared := float64(vec3Dot(edge, edge))
+	if from < len(d.model.BoneWeights) && to < len(d.model.BoneWeights) {
+		cost += boneWeightDistance(d.model.BoneWeights[from], d.model.BoneWeights[to]) * edgeLengthSquared
+	}
+	cost += morphDeltaDistance(d.morphDeltas[from], d.morphDeltas[to])
+	return cost
+}
+
+// canCollapse 检查折叠后网格是否仍然是流形，且没有三角形翻转
+func (d *decimator) canCollapse(from, to int) bool {
+	// 连接条件：两个顶点的公共邻居数应等于同时包含两者的三角形数
+	shared := 0
+	for _, t := range d.vertexTris[from] {
+		if d.triangleAlive[t] && triangleHas(d.triangles[t], to) {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return false
+	}
+	toNeighbors := make(map[int]struct{})
+	for _, n := range d.neighbors(to) {
+		toNeighbors[n] = struct{}{}
+	}
+	common := 0
+	for _, n := range d.neighbors(from) {
+		if _, ok := toNeighbors[n]; ok {
+			common++
+		}
+	}
+	if common != shared {
+		return false
+	}
+
+	for _, t := range d.vertexTris[from] {
+		if !d.triangleAlive[t] || triangleHas(d.triangles[t], to) {
+			continue
+		}
+		tri := d.triangles[t]
+		before := vec3Cross(vec3Sub(d.positions[tri[1]], d.positions[tri[0]]), vec3Sub(d.positions[tri[2]], d.positions[tri[0]]))
+		for i := range tri {
+			if tri[i] == from {
+				tri[i] = to
+			}
+		}
+		after := vec3Cross(vec3Sub(d.positions[tri[1]], d.positions[tri[0]]), vec3Sub(d.positions[tri[2]], d.positions[tri[0]]))
+		if vec3Length(after) == 0 || vec3Dot(vec3Normalize(before), vec3Normalize(after)) < 0.2 {
+			return false
+		}
+	}
+	return true
+}
+
+// collapse 将 from 折叠到 to，返回移除的三角形数
+func (d *decimator) collapse(from, to int) int {
+	removedTriangles := 0
+	for _, t := range d.vertexTris[from] {
+		if !d.triangleAlive[t] {
+			continue
+		}
+		if triangleHas(d.triangles[t], to) {
+			d.triangleAlive[t] = false
+			removedTriangles++
+			continue
+		}
+		for i := range d.triangles[t] {
+			if d.triangles[t][i] == from {
+				d.triangles[t][i] = to
+			}
+		}
+		d.vertexTris[to] = append(d.vertexTris[to], t)
+	}
+	d.vertexTris[from] = nil
+	d.quadrics[to].add(d.quadrics[from])
+	d.removed[from] = true
+	d.collapsedInto[from] = to
+
+	d.stamps[to]++
+	for _, n := range d.neighbors(to) {
+		d.stamps[n]++
+	}
+	d.pushCandidates(to)
+	for _, n := range d.neighbors(to) {
+		d.pushCandidates(n)
+	}
+	return removedTriangles
+}
+
+// apply 将减面结果写回模型，并移除不再使用的顶点
+func (d *decimator) apply() {
+	modelData := d.model
+	offset := 0
+	for s, subMesh := range modelData.SubMeshes {
+		count := len(subMesh) / 3
+		rebuilt := make([]int32, 0, len(subMesh))
+		for t := offset; t < offset+count; t++ {
+			if d.triangleAlive[t] {
+				tri := d.triangles[t]
+				rebuilt = append(rebuilt, int32(tri[0]), int32(tri[1]), int32(tri[2]))
+			}
+		}
+		modelData.SubMeshes[s] = rebuilt
+		offset += count
+	}
+
+	used := make([]bool, len(modelData.Vertices))
+	for _, subMesh := range modelData.SubMeshes {
+		for _, v := range subMesh {
+			used[v] = true
+		}
+	}
+	removeUnusedVertices(modelData, used, d.collapsedInto)
+}
+
+// removeUnusedVertices 移除 keep 为 false 的顶点，并重新映射子网格、形态键和皮肤厚度中的顶点索引
+// collapsedInto 记录被移除的顶点合并到的顶点，皮肤厚度引用被移除的顶点时会改为引用该顶点，可以为 nil
+func removeUnusedVertices(modelData *COM3D2.Model, keep []bool, collapsedInto []int) {
+	remap := buildIndexRemap(keep)
+	resolve := func(v int) int {
+		for steps := 0; v >= 0 && v < len(keep) && !keep[v] && steps < len(keep); steps++ {
+			if collapsedInto == nil {
+				return -1
+			}
+			v = collapsedInto[v]
+		}
+		if v < 0 || v >= len(keep) || !keep[v] {
+			return -1
+		}
+		return remap[v]
+	}
+
+	for _, subMesh := range modelData.SubMeshes {
+		for i, v := range subMesh {
+			subMesh[i] = int32(remap[v])
+		}
+	}
+	for _, morph := range modelData.MorphData {
+		entries := make([]int, 0, len(morph.Indices))
+		for i, vertexIndex := range morph.Indices {
+			if int(vertexIndex) < len(keep) && keep[vertexIndex] {
+				entries = append(entries, i)
+			}
+		}
+		indices := make([]int, 0, len(entries))
+		vertex := make([]COM3D2.Vector3, 0, len(entries))
+		normals := make([]COM3D2.Vector3, 0, len(entries))
+		var tangents []COM3D2.Quaternion
+		for _, i := range entries {
+			indices = append(indices, remap[morph.Indices[i]])
+			if i < len(morph.Vertex) {
+				vertex = append(vertex, morph.Vertex[i])
+			}
+			if i < len(morph.Normals) {
+				normals = append(normals, morph.Normals[i])
+			}
+			if i < len(morph.Tangents) {
+				tangents = append(tangents, morph.Tangents[i])
+			}
+		}
+		morph.Indices, morph.Vertex, morph.Normals = indices, vertex, normals
+		if len(morph.Tangents) > 0 {
+			morph.Tangents = tangents
+		}
+	}
+	if modelData.SkinThickness != nil {
+		for _, group := range modelData.SkinThickness.Groups {
+			if group == nil {
+				continue
+			}
+			for _, point := range group.Points {
+				if point == nil {
+					continue
+				}
+				for _, angle := range point.DistanceParAngle {
+					if angle != nil {
+						angle.VertexIndex = int32(max(resolve(int(angle.VertexIndex)), 0))
+					}
+				}
+			}
+		}
+	}
+
+	if len(modelData.Tangents) == len(keep) {
+		modelData.Tangents = filterByMask(modelData.Tangents, keep)
+	}
+	if len(modelData.BoneWeights) == len(keep) {
+		modelData.BoneWeights = filterByMask(modelData.BoneWeights, keep)
+	}
+	modelData.Vertices = filterByMask(modelData.Vertices, keep)
+	modelData.VertCount = int32(len(modelData.Vertices))
+}
+
+// triangleHas 判断三角形是否包含顶点 v
+func triangleHas(tri [3]int, v int) bool {
+	return tri[0] == v || tri[1] == v || tri[2] == v
+}
+
+// boneWeightDistance 两个顶点骨骼权重的 L1 距离，范围 [0, 2]
+func boneWeightDistance(a, b COM3D2.BoneWeight) float64 {
+	weights := make(map[int]float32, 8)
+	for _, inf := range getBoneInfluences(a) {
+		weights[inf.Index] += inf.Weight
+	}
+	for _, inf := range getBoneInfluences(b) {
+		weights[inf.Index] -= inf.Weight
+	}
+	var distance float64
+	for _, w := range weights {
+		distance += math.Abs(float64(w))
+	}
+	return distance
+}
+
+// morphDeltaDistance 两个顶点在所有形态键中偏移差的平方和，按形态键顺序累加以保证结果稳定
+func morphDeltaDistance(a, b []morphDelta) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	deltas := make(map[int]COM3D2.Vector3, len(a)+len(b))
+	for _, m := range a {
+		deltas[m.morph] = m.delta
+	}
+	for _, m := range b {
+		deltas[m.morph] = vec3Sub(deltas[m.morph], m.delta)
+	}
+	keys := make([]int, 0, len(deltas))
+	for k := range deltas {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var distance float64
+	for _, k := range keys {
+		distance += float64(vec3Dot(deltas[k], deltas[k]))
+	}
+	return distance
+}
@@ -0,0 +1,230 @@
+package COM3D2
+
+import (
+	"math"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 模型编辑中通用的向量运算和空间查找工具
+
+func vec3Add(a, b COM3D2.Vector3) COM3D2.Vector3 {
+	return COM3D2.Vector3{X: a.X + b.X, Y: a.Y + b.Y, Z: a.Z + b.Z}
+}
+
+func vec3Sub(a, b COM3D2.Vector3) COM3D2.Vector3 {
+	return COM3D2.Vector3{X: a.X - b.X, Y: a.Y - b.Y, Z: a.Z - b.Z}
+}
+
+func vec3Scale(a COM3D2.Vector3, s float32) COM3D2.Vector3 {
+	return COM3D2.Vector3{X: a.X * s, Y: a.Y * s, Z: a.Z * s}
+}
+
+func vec3Dot(a, b COM3D2.Vector3) float32 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func vec3Cross(a, b COM3D2.Vector3) COM3D2.Vector3 {
+	return COM3D2.Vector3{
+		X: a.Y*b.Z - a.Z*b.Y,
+		Y: a.Z*b.X - a.X*b.Z,
+		Z: a.X*b.Y - a.Y*b.X,
+	}
+}
+
+func vec3Length(a COM3D2.Vector3) float32 {
+	return float32(math.Sqrt(float64(vec3Dot(a, a))))
+}
+
+// vec3Normalize 归一化向量，长度为 0 时原样返回
+func vec3Normalize(a COM3D2.Vector3) COM3D2.Vector3 {
+	l := vec3Length(a)
+	if l == 0 {
+		return a
+	}
+	return vec3Scale(a, 1/l)
+}
+
+// vec3Angle 返回两个向量的夹角（弧度），任一向量长度为 0 时返回 0
+func vec3Angle(a, b COM3D2.Vector3) float32 {
+	la, lb := vec3Length(a), vec3Length(b)
+	if la == 0 || lb == 0 {
+		return 0
+	}
+	c := float64(vec3Dot(a, b) / (la * lb))
+	return float32(math.Acos(math.Max(-1, math.Min(1, c))))
+}
+
+// mirrorVec3 按轴（"X"、"Y"、"Z"）镜像向量，即对该分量取反
+func mirrorVec3(a COM3D2.Vector3, axis string) COM3D2.Vector3 {
+	switch axis {
+	case "Y":
+		a.Y = -a.Y
+	case "Z":
+		a.Z = -a.Z
+	default:
+		a.X = -a.X
+	}
+	return a
+}
+
+// positionGrid 按位置对顶点进行网格划分，用于快速查找某个位置附近的顶点
+type positionGrid struct {
+	cellSize float32
+	cells    map[[3]int32][]int
+	points   []COM3D2.Vector3
+}
+
+// newPositionGrid 使用指定的网格大小建立索引，cellSize 一般取查找半径
+func newPositionGrid(points []COM3D2.Vector3, cellSize float32) *positionGrid {
+	if cellSize <= 0 {
+		cellSize = 1e-4
+	}
+	g := &positionGrid{
+		cellSize: cellSize,
+		cells:    make(map[[3]int32][]int, len(points)),
+		points:   points,
+	}
+	for i, p := range points {
+		key := g.cellOf(p)
+		g.cells[key] = append(g.cells[key], i)
+	}
+	return g
+}
+
+func (g *positionGrid) cellOf(p COM3D2.Vector3) [3]int32 {
+	return gridCell(p, g.cellSize)
+}
+
+// gridCell 返回位置所在的网格坐标
+func gridCell(p COM3D2.Vector3, cellSize float32) [3]int32 {
+	return [3]int32{
+		int32(math.Floor(float64(p.X / cellSize))),
+		int32(math.Floor(float64(p.Y / cellSize))),
+		int32(math.Floor(float64(p.Z / cellSize))),
+	}
+}
+
+// nearest 返回距离 p 不超过 maxDistance 的最近点索引，没有时返回 -1
+// maxDistance 不应大于 cellSize，否则可能漏找
+func (g *positionGrid) nearest(p COM3D2.Vector3, maxDistance float32) int {
+	best := -1
+	bestDist := maxDistance * maxDistance
+	center := g.cellOf(p)
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dz := int32(-1); dz <= 1; dz++ {
+				for _, i := range g.cells[[3]int32{center[0] + dx, center[1] + dy, center[2] + dz}] {
+					d := vec3Sub(g.points[i], p)
+					dist := vec3Dot(d, d)
+					if dist <= bestDist {
+						best = i
+						bestDist = dist
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// within 返回距离 p 不超过 maxDistance 的所有点索引
+// maxDistance 不应大于 cellSize，否则可能漏找
+func (g *positionGrid) within(p COM3D2.Vector3, maxDistance float32) []int {
+	var found []int
+	limit := maxDistance * maxDistance
+	center := g.cellOf(p)
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dz := int32(-1); dz <= 1; dz++ {
+				for _, i := range g.cells[[3]int32{center[0] + dx, center[1] + dy, center[2] + dz}] {
+					d := vec3Sub(g.points[i], p)
+					if vec3Dot(d, d) <= limit {
+						found = append(found, i)
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// vertexPositions 提取模型所有顶点的位置
+func vertexPositions(modelData *COM3D2.Model) []COM3D2.Vector3 {
+	positions := make([]COM3D2.Vector3, len(modelData.Vertices))
+	for i := range modelData.Vertices {
+		positions[i] = modelData.Vertices[i].Position
+	}
+	return positions
+}
+
+// rayTriangle 计算射线与三角形的交点（Möller–Trumbore 算法），返回射线参数 t，不相交时第二个返回值为 false
+// 三角形双面有效
+func rayTriangle(origin, dir, a, b, c COM3D2.Vector3) (float32, bool) {
+	const eps = 1e-8
+	e1, e2 := vec3Sub(b, a), vec3Sub(c, a)
+	p := vec3Cross(dir, e2)
+	det := vec3Dot(e1, p)
+	if det > -eps && det < eps {
+		return 0, false
+	}
+	inv := 1 / det
+	s := vec3Sub(origin, a)
+	u := vec3Dot(s, p) * inv
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := vec3Cross(s, e1)
+	v := vec3Dot(dir, q) * inv
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+	t := vec3Dot(e2, q) * inv
+	return t, t > 0
+}
+
+// closestPointOnTriangle 返回三角形上离 p 最近的点的重心坐标（对应 a、b、c 的权重）
+// 参考 Ericson《Real-Time Collision Detection》5.1.5
+func closestPointOnTriangle(p, a, b, c COM3D2.Vector3) [3]float32 {
+	ab, ac, ap := vec3Sub(b, a), vec3Sub(c, a), vec3Sub(p, a)
+	d1, d2 := vec3Dot(ab, ap), vec3Dot(ac, ap)
+	if d1 <= 0 && d2 <= 0 {
+		return [3]float32{1, 0, 0}
+	}
+	bp := vec3Sub(p, b)
+	d3, d4 := vec3Dot(ab, bp), vec3Dot(ac, bp)
+	if d3 >= 0 && d4 <= d3 {
+		return [3]float32{0, 1, 0}
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		v := d1 / (d1 - d3)
+		return [3]float32{1 - v, v, 0}
+	}
+	cp := vec3Sub(p, c)
+	d5, d6 := vec3Dot(ab, cp), vec3Dot(ac, cp)
+	if d6 >= 0 && d5 <= d6 {
+		return [3]float32{0, 0, 1}
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		w := d2 / (d2 - d6)
+		return [3]float32{1 - w, 0, w}
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		w := (d4 - d3) / ((d4 - d3) + (d5 - d6))
+		return [3]float32{0, 1 - w, w}
+	}
+	denom := va + vb + vc
+	if denom == 0 {
+		return [3]float32{1, 0, 0}
+	}
+	v, w := vb/denom, vc/denom
+	return [3]float32{1 - v - w, v, w}
+}
+
+// barycentricPoint 根据重心坐标计算三角形上的点
+func barycentricPoint(weights [3]float32, a, b, c COM3D2.Vector3) COM3D2.Vector3 {
+	return vec3Add(vec3Add(vec3Scale(a, weights[0]), vec3Scale(b, weights[1])), vec3Scale(c, weights[2]))
+}
@@ -0,0 +1,279 @@
+package COM3D2
+
+import (
+	"math"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 矩阵和四元数运算，约定与 Unity 一致：
+// 矩阵为列主序，下标为 row + col*4，与 COM3D2.Matrix4x4（BindPoses）的存储顺序相同
+// 欧拉角按 Unity 的 Z、X、Y 顺序旋转
+
+// mat4 4x4 矩阵，使用 float64 计算以减少累积误差
+type mat4 [16]float64
+
+func mat4Identity() mat4 {
+	return mat4{0: 1, 5: 1, 10: 1, 15: 1}
+}
+
+// mat4FromMatrix4x4 将 COM3D2.Matrix4x4 转换为 mat4
+func mat4FromMatrix4x4(m COM3D2.Matrix4x4) mat4 {
+	var r mat4
+	for i := range m {
+		r[i] = float64(m[i])
+	}
+	return r
+}
+
+// toMatrix4x4 将 mat4 转换为 COM3D2.Matrix4x4
+func (m mat4) toMatrix4x4() COM3D2.Matrix4x4 {
+	var r COM3D2.Matrix4x4
+	for i := range m {
+		r[i] = float32(m[i])
+	}
+	return r
+}
+
+func (m mat4) at(row, col int) float64 {
+	return m[row+col*4]
+}
+
+func mat4Mul(a, b mat4) mat4 {
+	var r mat4
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += a.at(row, k) * b.at(k, col)
+			}
+			r[row+col*4] = sum
+		}
+	}
+	return r
+}
+
+// mat4TRS 等同于 Unity 的 Matrix4x4.TRS
+func mat4TRS(pos COM3D2.Vector3, rot COM3D2.Quaternion, scale COM3D2.Vector3) mat4 {
+	x, y, z, w := float64(rot.X), float64(rot.Y), float64(rot.Z), float64(rot.W)
+	sx, sy, sz := float64(scale.X), float64(scale.Y), float64(scale.Z)
+	return mat4{
+		(1 - 2*(y*y+z*z)) * sx, 2 * (x*y + w*z) * sx, 2 * (x*z - w*y) * sx, 0,
+		2 * (x*y - w*z) * sy, (1 - 2*(x*x+z*z)) * sy, 2 * (y*z + w*x) * sy, 0,
+		2 * (x*z + w*y) * sz, 2 * (y*z - w*x) * sz, (1 - 2*(x*x+y*y)) * sz, 0,
+		float64(pos.X), float64(pos.Y), float64(pos.Z), 1,
+	}
+}
+
+// mulPoint 变换点（包含平移）
+func (m mat4) mulPoint(v COM3D2.Vector3) COM3D2.Vector3 {
+	x, y, z := float64(v.X), float64(v.Y), float64(v.Z)
+	return COM3D2.Vector3{
+		X: float32(m[0]*x + m[4]*y + m[8]*z + m[12]),
+		Y: float32(m[1]*x + m[5]*y + m[9]*z + m[13]),
+		Z: float32(m[2]*x + m[6]*y + m[10]*z + m[14]),
+	}
+}
+
+// mulVector 变换方向（不包含平移）
+func (m mat4) mulVector(v COM3D2.Vector3) COM3D2.Vector3 {
+	x, y, z := float64(v.X), float64(v.Y), float64(v.Z)
+	return COM3D2.Vector3{
+		X: float32(m[0]*x + m[4]*y + m[8]*z),
+		Y: float32(m[1]*x + m[5]*y + m[9]*z),
+		Z: float32(m[2]*x + m[6]*y + m[10]*z),
+	}
+}
+
+// translation 返回矩阵的平移部分
+func (m mat4) translation() COM3D2.Vector3 {
+	return COM3D2.Vector3{X: float32(m[12]), Y: float32(m[13]), Z: float32(m[14])}
+}
+
+// determinant3 返回左上 3x3 部分的行列式，小于 0 表示包含镜像
+func (m mat4) determinant3() float64 {
+	return m[0]*(m[5]*m[10]-m[9]*m[6]) -
+		m[4]*(m[1]*m[10]-m[9]*m[2]) +
+		m[8]*(m[1]*m[6]-m[5]*m[2])
+}
+
+// inverse 求逆矩阵，矩阵不可逆时第二个返回值为 false
+func (m mat4) inverse() (mat4, bool) {
+	var inv mat4
+	inv[0] = m[5]*m[10]*m[15] - m[5]*m[11]*m[14] - m[9]*m[6]*m[15] + m[9]*m[7]*m[14] + m[13]*m[6]*m[11] - m[13]*m[7]*m[10]
+	inv[4] = -m[4]*m[10]*m[15] + m[4]*m[11]*m[14] + m[8]*m[6]*m[15] - m[8]*m[7]*m[14] - m[12]*m[6]*m[11] + m[12]*m[7]*m[10]
+	inv[8] = m[4]*m[9]*m[15] - m[4]*m[11]*m[13] - m[8]*m[5]*m[15] + m[8]*m[7]*m[13] + m[12]*m[5]*m[11] - m[12]*m[7]*m[9]
+	inv[12] = -m[4]*m[9]*m[14] + m[4]*m[10]*m[13] + m[8]*m[5]*m[14] - m[8]*m[6]*m[13] - m[12]*m[5]*m[10] + m[12]*m[6]*m[9]
+	inv[1] = -m[1]*m[10]*m[15] + m[1]*m[11]*m[14] + m[9]*m[2]*m[15] - m[9]*m[3]*m[14] - m[13]*m[2]*m[11] + m[13]*m[3]*m[10]
+	inv[5] = m[0]*m[10]*m[15] - m[0]*m[11]*m[14] - m[8]*m[2]*m[15] + m[8]*m[3]*m[14] + m[12]*m[2]*m[11] - m[12]*m[3]*m[10]
+	inv[9] = -m[0]*m[9]*m[15] + m[0]*m[11]*m[13] + m[8]*m[1]*m[15] - m[8]*m[3]*m[13] - m[12]*m[1]*m[11] + m[12]*m[3]*m[9]
+	inv[13] = m[0]*m[9]*m[14] - m[0]*m[10]*m[13] - m[8]*m[1]*m[14] + m[8]*m[2]*m[13] + m[12]*m[1]*m[10] - m[12]*m[2]*m[9]
+	inv[2] = m[1]*m[6]*m[15] - m[1]*m[7]*m[14] - m[5]*m[2]*m[15] + m[5]*m[3]*m[14] + m[13]*m[2]*m[7] - m[13]*m[3]*m[6]
+	inv[6] = -m[0]*m[6]*m[15] + m[0]*m[7]*m[14] + m[4]*m[2]*m[15] - m[4]*m[3]*m[14] - m[12]*m[2]*m[7] + m[12]*m[3]*m[6]
+	inv[10] = m[0]*m[5]*m[15] - m[0]*m[7]*m[13] - m[4]*m[1]*m[15] + m[4]*m[3]*m[13] + m[12]*m[1]*m[7] - m[12]*m[3]*m[5]
+	inv[14] = -m[0]*m[5]*m[14] + m[0]*m[6]*m[13] + m[4]*m[1]*m[14] - m[4]*m[2]*m[13] - m[12]*m[1]*m[6] + m[12]*m[2]*m[5]
+	inv[3] = -m[1]*m[6]*m[11] + m[1]*m[7]*m[10] + m[5]*m[2]*m[11] - m[5]*m[3]*m[10] - m[9]*m[2]*m[7] + m[9]*m[3]*m[6]
+	inv[7] = m[0]*m[6]*m[11] - m[0]*m[7]*m[10] - m[4]*m[2]*m[11] + m[4]*m[3]*m[10] + m[8]*m[2]*m[7] - m[8]*m[3]*m[6]
+	inv[11] = -m[0]*m[5]*m[11] + m[0]*m[7]*m[9] + m[4]*m[1]*m[11] - m[4]*m[3]*m[9] - m[8]*m[1]*m[7] + m[8]*m[3]*m[5]
+	inv[15] = m[0]*m[5]*m[10] - m[0]*m[6]*m[9] - m[4]*m[1]*m[10] + m[4]*m[2]*m[9] + m[8]*m[1]*m[6] - m[8]*m[2]*m[5]
+
+	det := m[0]*inv[0] + m[1]*inv[4] + m[2]*inv[8] + m[3]*inv[12]
+	if det == 0 || math.IsNaN(det) {
+		return mat4{}, false
+	}
+	for i := range inv {
+		inv[i] /= det
+	}
+	return inv, true
+}
+
+// normalMatrix 返回用于变换法线的矩阵（左上 3x3 的逆转置）
+func (m mat4) normalMatrix() mat4 {
+	linear := m
+	linear[12], linear[13], linear[14] = 0, 0, 0
+	inv, ok := linear.inverse()
+	if !ok {
+		return linear
+	}
+	var r mat4
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			r[row+col*4] = inv[col+row*4]
+		}
+	}
+	return r
+}
+
+func quatIdentity() COM3D2.Quaternion {
+	return COM3D2.Quaternion{W: 1}
+}
+
+// quatMul 四元数乘法，a*b 表示先旋转 b 再旋转 a
+func quatMul(a, b COM3D2.Quaternion) COM3D2.Quaternion {
+	return COM3D2.Quaternion{
+		X: a.W*b.X + a.X*b.W + a.Y*b.Z - a.Z*b.Y,
+		Y: a.W*b.Y + a.Y*b.W + a.Z*b.X - a.X*b.Z,
+		Z: a.W*b.Z + a.Z*b.W + a.X*b.Y - a.Y*b.X,
+		W: a.W*b.W - a.X*b.X - a.Y*b.Y - a.Z*b.Z,
+	}
+}
+
+// quatConjugate 共轭，对于单位四元数即为逆
+func quatConjugate(q COM3D2.Quaternion) COM3D2.Quaternion {
+	return COM3D2.Quaternion{X: -q.X, Y: -q.Y, Z: -q.Z, W: q.W}
+}
+
+func quatNormalize(q COM3D2.Quaternion) COM3D2.Quaternion {
+	l := float32(math.Sqrt(float64(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W)))
+	if l == 0 {
+		return quatIdentity()
+	}
+	return COM3D2.Quaternion{X: q.X / l, Y: q.Y / l, Z: q.Z / l, W: q.W / l}
+}
+
+// quatRotate 用四元数旋转向量
+func quatRotate(q COM3D2.Quaternion, v COM3D2.Vector3) COM3D2.Vector3 {
+	u := COM3D2.Vector3{X: q.X, Y: q.Y, Z: q.Z}
+	t := vec3Scale(vec3Cross(u, v), 2)
+	return vec3Add(vec3Add(v, vec3Scale(t, q.W)), vec3Cross(u, t))
+}
+
+// quatFromAxisAngle 由旋转轴和角度（弧度）构造四元数
+func quatFromAxisAngle(axis COM3D2.Vector3, angle float64) COM3D2.Quaternion {
+	axis = vec3Normalize(axis)
+	s := float32(math.Sin(angle / 2))
+	return COM3D2.Quaternion{X: axis.X * s, Y: axis.Y * s, Z: axis.Z * s, W: float32(math.Cos(angle / 2))}
+}
+
+// quatFromEuler 等同于 Unity 的 Quaternion.Euler，参数为角度
+func quatFromEuler(degrees COM3D2.Vector3) COM3D2.Quaternion {
+	toRad := math.Pi / 180
+	qx := quatFromAxisAngle(COM3D2.Vector3{X: 1}, float64(degrees.X)*toRad)
+	qy := quatFromAxisAngle(COM3D2.Vector3{Y: 1}, float64(degrees.Y)*toRad)
+	qz := quatFromAxisAngle(COM3D2.Vector3{Z: 1}, float64(degrees.Z)*toRad)
+	return quatMul(quatMul(qy, qx), qz)
+}
+
+// quatSlerp 球面线性插值，沿最短路径从 a 插值到 b
+func quatSlerp(a, b COM3D2.Quaternion, t float32) COM3D2.Quaternion {
+	dot := float64(a.X*b.X + a.Y*b.Y + a.Z*b.Z + a.W*b.W)
+	if dot < 0 {
+		b = COM3D2.Quaternion{X: -b.X, Y: -b.Y, Z: -b.Z, W: -b.W}
+		dot = -dot
+	}
+	wa, wb := 1-float64(t), float64(t)
+	if dot < 0.9995 {
+		theta := math.Acos(dot)
+		sin := math.Sin(theta)
+		wa = math.Sin((1-float64(t))*theta) / sin
+		wb = math.Sin(float64(t)*theta) / sin
+	}
+	return quatNormalize(COM3D2.Quaternion{
+		X: float32(wa)*a.X + float32(wb)*b.X,
+		Y: float32(wa)*a.Y + float32(wb)*b.Y,
+		Z: float32(wa)*a.Z + float32(wb)*b.Z,
+		W: float32(wa)*a.W + float32(wb)*b.W,
+	})
+}
+
+// boneLocalScale 返回骨骼的局部缩放，没有缩放数据时为 1
+func boneLocalScale(bone *COM3D2.Bone) COM3D2.Vector3 {
+	if bone.HasScale && bone.Scale != nil {
+		return *bone.Scale
+	}
+	return COM3D2.Vector3{X: 1, Y: 1, Z: 1}
+}
+
+// boneWorldMatrices 根据 Bones 层级计算每个骨骼在模型空间中的静止姿态矩阵
+// 父骨骼索引无效或出现循环时，将该骨骼视为根骨骼
+func boneWorldMatrices(bones []*COM3D2.Bone) []mat4 {
+	world := make([]mat4, len(bones))
+	state := make([]uint8, len(bones)) // 0 未计算，1 计算中，2 已完成
+	var compute func(i int) mat4
+	compute = func(i int) mat4 {
+		if state[i] == 2 {
+			return world[i]
+		}
+		state[i] = 1
+		bone := bones[i]
+		local := mat4TRS(bone.Position, bone.Rotation, boneLocalScale(bone))
+		parent := int(bone.ParentIndex)
+		if parent >= 0 && parent < len(bones) && parent != i && state[parent] != 1 {
+			world[i] = mat4Mul(compute(parent), local)
+		} else {
+			world[i] = local
+		}
+		state[i] = 2
+		return world[i]
+	}
+	for i := range bones {
+		compute(i)
+	}
+	return world
+}
+
+// boneWorldRotations 根据 Bones 层级计算每个骨骼在模型空间中的静止姿态旋转（忽略缩放）
+func boneWorldRotations(bones []*COM3D2.Bone) []COM3D2.Quaternion {
+	world := make([]COM3D2.Quaternion, len(bones))
+	state := make([]uint8, len(bones))
+	var compute func(i int) COM3D2.Quaternion
+	compute = func(i int) COM3D2.Quaternion {
+		if state[i] == 2 {
+			return world[i]
+		}
+		state[i] = 1
+		bone := bones[i]
+		parent := int(bone.ParentIndex)
+		if parent >= 0 && parent < len(bones) && parent != i && state[parent] != 1 {
+			world[i] = quatNormalize(quatMul(compute(parent), bone.Rotation))
+		} else {
+			world[i] = quatNormalize(bone.Rotation)
+		}
+		state[i] = 2
+		return world[i]
+	}
+	for i := range bones {
+		compute(i)
+	}
+	return world
+}
@@ -0,0 +1,269 @@
+package COM3D2
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// ModelMergeOptions 合并模型的选项
+type ModelMergeOptions struct {
+	ParentBone string `json:"ParentBone"` // 源模型中父骨骼在目标模型中不存在的骨骼会挂到该骨骼下，为空时使用目标模型的 RootBoneName
+}
+
+// ModelMergeResult 合并模型的结果
+type ModelMergeResult struct {
+	AddedBones     []string `json:"AddedBones"`     // 新增到目标模型的骨骼
+	SharedBones    []string `json:"SharedBones"`    // 两个模型共有、按名称合并的骨骼
+	AddedVertices  int      `json:"AddedVertices"`  // 新增的顶点数
+	AddedSubMeshes int      `json:"AddedSubMeshes"` // 新增的子网格数
+	AddedMorphs    []string `json:"AddedMorphs"`    // 新增的形态键
+	MergedMorphs   []string `json:"MergedMorphs"`   // 与目标模型同名、合并为一个的形态键
+	Warnings       []string `json:"Warnings"`       // 合并过程中的警告
+}
+
+// MergeModel 将 sourcePath 模型的顶点、子网格、材质和形态键追加到 inputPath 模型中，写入 outputPath
+// 骨骼按名称合并，目标模型中没有的骨骼会被添加，并保持其在模型空间中的静止姿态不变
+// 同名形态键会合并为一个，目标模型的 SkinThickness 保持不变
+func (m *ModelService) MergeModel(inputPath string, sourcePath string, outputPath string, options ModelMergeOptions) (result ModelMergeResult, err error) {
+	sourceData, err := m.ReadModelFile(sourcePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read source model file: %w", err)
+	}
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = mergeModel(modelData, sourceData, options)
+		return err
+	})
+	return result, err
+}
+
+// mergeModel 将 source 合并到 target 中
+func mergeModel(target *COM3D2.Model, source *COM3D2.Model, options ModelMergeOptions) (result ModelMergeResult, err error) {
+	result.Warnings = []string{}
+	warn := func(format string, args ...any) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+	if len(source.BoneWeights) != len(source.Vertices) {
+		return result, fmt.Errorf("source model has %d bone weights but %d vertices", len(source.BoneWeights), len(source.Vertices))
+	}
+
+	parentBone := options.ParentBone
+	if parentBone == "" {
+		parentBone = target.RootBoneName
+	}
+	parentIndex := findBone(target, parentBone)
+	if parentIndex < 0 {
+		return result, fmt.Errorf("parent bone %q not found in target model", parentBone)
+	}
+
+	mergeBones(target, source, parentIndex, &result)
+
+	// 蒙皮骨骼：目标模型已有且 BindPose 一致的沿用目标模型的，否则追加源模型的 BindPose
+	// 同名但 BindPose 不同（例如两个模型的网格空间不同）时追加一个同名的蒙皮骨骼，使源模型的顶点仍按原 BindPose 变形
+	skinRemap := make([]int, len(source.BoneNames))
+	for i, name := range source.BoneNames {
+		if i >= len(source.BindPoses) {
+			return result, fmt.Errorf("source model has %d bone names but only %d bind poses", len(source.BoneNames), len(source.BindPoses))
+		}
+		if existing := slices.Index(target.BoneNames, name); existing >= 0 && existing < len(target.BindPoses) {
+			distance, angle, ok := bindPoseDifference(target.BindPoses[existing], source.BindPoses[i])
+			if ok && distance <= defaultSkeletonPositionTolerance && angle <= defaultSkeletonAngleTolerance {
+				skinRemap[i] = existing
+				continue
+			}
+			warn("bind pose of skin bone %q differs between the models (%.4f apart, %.2f degrees), added a separate skin entry for the source vertices", name, distance, angle)
+		}
+		skinRemap[i] = len(target.BoneNames)
+		target.BoneNames = append(target.BoneNames, name)
+		target.BindPoses = append(target.BindPoses, source.BindPoses[i])
+	}
+	if len(target.BoneNames) > 0xFFFF {
+		return result, fmt.Errorf("merged model has %d skin bones, more than the %d supported", len(target.BoneNames), 0xFFFF)
+	}
+
+	// 顶点、切线和权重
+	vertexOffset := len(target.Vertices)
+	mergeTangents(target, source, warn)
+	target.Vertices = append(target.Vertices, source.Vertices...)
+	for _, bw := range source.BoneWeights {
+		influences := getBoneInfluences(bw)
+		for i := range influences {
+			if influences[i].Index < len(skinRemap) {
+				influences[i].Index = skinRemap[influences[i].Index]
+			} else if influences[i].Weight > 0 {
+				return result, fmt.Errorf("source bone weight references skin bone %d, but the source model only has %d", influences[i].Index, len(skinRemap))
+			} else {
+				influences[i].Index = 0
+			}
+		}
+		setBoneInfluences(&bw, influences[:])
+		target.BoneWeights = append(target.BoneWeights, bw)
+	}
+	result.AddedVertices = len(source.Vertices)
+
+	// 子网格与材质：目标模型中超出子网格数量的多 Pass 材质作用于原来的最后一个子网格，合并后不再适用，因此被丢弃
+	targetSubMeshCount := len(target.SubMeshes)
+	if len(target.Materials) > targetSubMeshCount {
+		warn("%d extra material(s) of the target model dropped: they rendered the last submesh again, which is no longer last after merging", len(target.Materials)-targetSubMeshCount)
+		target.Materials = target.Materials[:targetSubMeshCount]
+	}
+	for len(target.Materials) < targetSubMeshCount {
+		target.Materials = append(target.Materials, nil)
+		warn("target submesh %d had no material", len(target.Materials)-1)
+	}
+	for _, subMesh := range source.SubMeshes {
+		shifted := make([]int32, len(subMesh))
+		for i, index := range subMesh {
+			shifted[i] = index + int32(vertexOffset)
+		}
+		target.SubMeshes = append(target.SubMeshes, shifted)
+	}
+	for _, material := range source.Materials {
+		copied, err := cloneMaterial(material)
+		if err != nil {
+			return result, err
+		}
+		target.Materials = append(target.Materials, copied)
+	}
+	result.AddedSubMeshes = len(source.SubMeshes)
+
+	// 形态键
+	for _, morph := range source.MorphData {
+		copied := cloneMorph(morph)
+		for i := range copied.Indices {
+			copied.Indices[i] += vertexOffset
+		}
+		index, err := findMorph(target, morph.Name)
+		if err != nil {
+			target.MorphData = append(target.MorphData, copied)
+			result.AddedMorphs = append(result.AddedMorphs, morph.Name)
+			continue
+		}
+		existing := target.MorphData[index]
+		if (len(existing.Tangents) > 0) != (len(copied.Tangents) > 0) {
+			// 一方有切线一方没有时以 0 补全，保持数组长度一致
+			if len(existing.Tangents) == 0 {
+				existing.Tangents = make([]COM3D2.Quaternion, len(existing.Indices))
+			}
+			if len(copied.Tangents) == 0 {
+				copied.Tangents = make([]COM3D2.Quaternion, len(copied.Indices))
+			}
+		}
+		existing.Indices = append(existing.Indices, copied.Indices...)
+		existing.Vertex = append(existing.Vertex, copied.Vertex...)
+		existing.Normals = append(existing.Normals, copied.Normals...)
+		existing.Tangents = append(existing.Tangents, copied.Tangents...)
+		result.MergedMorphs = append(result.MergedMorphs, morph.Name)
+	}
+
+	if source.SkinThickness != nil {
+		warn("SkinThickness of the source model was not merged")
+	}
+	if source.Version > target.Version {
+		warn("source model version %d is newer than target model version %d, some data may not be saved, consider ConvertModelVersion", source.Version, target.Version)
+	}
+
+	target.VertCount = int32(len(target.Vertices))
+	target.SubMeshCount = int32(len(target.SubMeshes))
+	target.BoneCount = int32(len(target.BoneNames))
+	return result, nil
+}
+
+// mergeBones 按名称合并骨骼
+// 新增骨骼的局部变换按其在目标模型中的父骨骼重新计算，使其在模型空间中的静止姿态与源模型一致
+func mergeBones(target *COM3D2.Model, source *COM3D2.Model, parentIndex int, result *ModelMergeResult) {
+	sourceWorld := boneWorldMatrices(source.Bones)
+	sourceRotations := boneWorldRotations(source.Bones)
+	targetWorld := boneWorldMatrices(target.Bones)
+	targetRotations := boneWorldRotations(target.Bones)
+
+	remap := make([]int, len(source.Bones))
+	added := make([]bool, len(source.Bones))
+	for i, bone := range source.Bones {
+		if existing := findBone(target, bone.Name); existing >= 0 {
+			remap[i] = existing
+			result.SharedBones = append(result.SharedBones, bone.Name)
+			continue
+		}
+		remap[i] = len(target.Bones)
+		added[i] = true
+		target.Bones = append(target.Bones, &COM3D2.Bone{Name: bone.Name, HasScale: bone.HasScale})
+		result.AddedBones = append(result.AddedBones, bone.Name)
+	}
+
+	for i, bone := range source.Bones {
+		if !added[i] {
+			continue
+		}
+		newBone := target.Bones[remap[i]]
+		if bone.Scale != nil {
+			scale := *bone.Scale
+			newBone.Scale = &scale
+		}
+
+		// 父骨骼在目标模型中的世界矩阵：已有骨骼取目标模型的，新增骨骼与源模型一致
+		var parentWorld mat4
+		var parentRotation COM3D2.Quaternion
+		sourceParent := int(bone.ParentIndex)
+		switch {
+		case sourceParent >= 0 && sourceParent < len(source.Bones) && added[sourceParent]:
+			newBone.ParentIndex = int32(remap[sourceParent])
+			newBone.Position = bone.Position
+			newBone.Rotation = bone.Rotation
+			continue
+		case sourceParent >= 0 && sourceParent < len(source.Bones):
+			newBone.ParentIndex = int32(remap[sourceParent])
+			parentWorld, parentRotation = targetWorld[remap[sourceParent]], targetRotations[remap[sourceParent]]
+		default:
+			newBone.ParentIndex = int32(parentIndex)
+			parentWorld, parentRotation = targetWorld[parentIndex], targetRotations[parentIndex]
+		}
+
+		parentInverse, ok := parentWorld.inverse()
+		if !ok {
+			parentInverse = mat4Identity()
+		}
+		newBone.Position = parentInverse.mulPoint(sourceWorld[i].translation())
+		newBone.Rotation = quatNormalize(quatMul(quatConjugate(parentRotation), sourceRotations[i]))
+	}
+}
+
+// mergeTangents 两个模型中只有一方有切线时，为另一方按 UV 计算切线
+func mergeTangents(target *COM3D2.Model, source *COM3D2.Model, warn func(format string, args ...any)) {
+	targetHas := len(target.Tangents) == len(target.Vertices) && len(target.Vertices) > 0
+	sourceHas := len(source.Tangents) == len(source.Vertices) && len(source.Vertices) > 0
+	switch {
+	case targetHas && sourceHas:
+		target.Tangents = append(target.Tangents, source.Tangents...)
+	case targetHas:
+		target.Tangents = append(target.Tangents, modelTangents(source)...)
+		warn("source model has no tangents, they were calculated from UVs")
+	case sourceHas:
+		target.Tangents = append(modelTangents(target), source.Tangents...)
+		warn("target model has no tangents, they were calculated from UVs")
+	default:
+		target.Tangents = nil
+	}
+}
+
+// modelTangents 按模型所有子网格计算切线，无效的三角形会被跳过
+func modelTangents(modelData *COM3D2.Model) []COM3D2.Quaternion {
+	positions := vertexPositions(modelData)
+	normals := make([]COM3D2.Vector3, len(modelData.Vertices))
+	uvs := make([]COM3D2.Vector2, len(modelData.Vertices))
+	for i := range modelData.Vertices {
+		normals[i] = modelData.Vertices[i].Normal
+		uvs[i] = modelData.Vertices[i].UV
+	}
+	var triangles [][3]int
+	for _, subMesh := range modelData.SubMeshes {
+		for t := 0; t+2 < len(subMesh); t += 3 {
+			tri := [3]int{int(subMesh[t]), int(subMesh[t+1]), int(subMesh[t+2])}
+			if tri[0] >= 0 && tri[1] >= 0 && tri[2] >= 0 && tri[0] < len(positions) && tri[1] < len(positions) && tri[2] < len(positions) {
+				triangles = append(triangles, tri)
+			}
+		}
+	}
+	return computeVertexTangents(positions, normals, uvs, triangles)
+}
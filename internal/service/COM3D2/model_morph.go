@@ -0,0 +1,319 @@
+package COM3D2
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// MorphInfo 形态键（Shape Key）的概要信息
+type MorphInfo struct {
+	Index       int    `json:"Index"`       // 在 MorphData 中的位置
+	Name        string `json:"Name"`        // 名称
+	VertexCount int    `json:"VertexCount"` // 受影响的顶点数
+	HasTangents bool   `json:"HasTangents"` // 是否包含切线数据
+}
+
+// MorphMirrorResult 镜像形态键的结果
+type MorphMirrorResult struct {
+	MirroredVertexCount  int `json:"MirroredVertexCount"`  // 成功找到对称顶点的数量
+	UnmatchedVertexCount int `json:"UnmatchedVertexCount"` // 找不到对称顶点而被丢弃的数量
+}
+
+// ListMorphs 读取 .model 文件，返回所有形态键的概要信息
+func (m *ModelService) ListMorphs(path string) ([]MorphInfo, error) {
+	modelData, err := m.ReadModelFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model file: %w", err)
+	}
+	return listMorphs(modelData), nil
+}
+
+// listMorphs 返回模型中所有形态键的概要
+func listMorphs(modelData *COM3D2.Model) []MorphInfo {
+	infos := make([]MorphInfo, 0, len(modelData.MorphData))
+	for i, morph := range modelData.MorphData {
+		infos = append(infos, MorphInfo{
+			Index:       i,
+			Name:        morph.Name,
+			VertexCount: len(morph.Indices),
+			HasTangents: len(morph.Tangents) > 0,
+		})
+	}
+	return infos
+}
+
+// RenameMorph 重命名形态键，新名称不能与已有形态键重复
+func (m *ModelService) RenameMorph(inputPath string, outputPath string, oldName string, newName string) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		index, err := findMorph(modelData, oldName)
+		if err != nil {
+			return err
+		}
+		if oldName == newName {
+			return nil
+		}
+		if err := checkMorphNameFree(modelData, newName); err != nil {
+			return err
+		}
+		modelData.MorphData[index].Name = newName
+		return nil
+	})
+}
+
+// DeleteMorphs 删除指定名称的形态键
+func (m *ModelService) DeleteMorphs(inputPath string, outputPath string, names []string) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		keep := make([]bool, len(modelData.MorphData))
+		for i := range keep {
+			keep[i] = true
+		}
+		for _, name := range names {
+			index, err := findMorph(modelData, name)
+			if err != nil {
+				return err
+			}
+			keep[index] = false
+		}
+		modelData.MorphData = filterByMask(modelData.MorphData, keep)
+		return nil
+	})
+}
+
+// DuplicateMorph 复制形态键，副本追加在末尾
+func (m *ModelService) DuplicateMorph(inputPath string, outputPath string, name string, newName string) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		index, err := findMorph(modelData, name)
+		if err != nil {
+			return err
+		}
+		if err := checkMorphNameFree(modelData, newName); err != nil {
+			return err
+		}
+		duplicate := cloneMorph(modelData.MorphData[index])
+		duplicate.Name = newName
+		modelData.MorphData = append(modelData.MorphData, duplicate)
+		return nil
+	})
+}
+
+// ReorderMorphs 按 names 的顺序重新排列形态键，未列出的形态键保持原有相对顺序排在后面
+func (m *ModelService) ReorderMorphs(inputPath string, outputPath string, names []string) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		placed := make([]bool, len(modelData.MorphData))
+		ordered := make([]*COM3D2.MorphData, 0, len(modelData.MorphData))
+		for _, name := range names {
+			index, err := findMorph(modelData, name)
+			if err != nil {
+				return err
+			}
+			if placed[index] {
+				return fmt.Errorf("morph %q is listed more than once", name)
+			}
+			placed[index] = true
+			ordered = append(ordered, modelData.MorphData[index])
+		}
+		for i, morph := range modelData.MorphData {
+			if !placed[i] {
+				ordered = append(ordered, morph)
+			}
+		}
+		modelData.MorphData = ordered
+		return nil
+	})
+}
+
+// CopyMorphsFromModel 从 sourcePath 的模型复制形态键到 inputPath 的模型，两个模型的顶点数和顺序必须一致
+// names 为空时复制全部形态键；overwrite 为 true 时覆盖同名形态键，否则遇到同名时报错
+func (m *ModelService) CopyMorphsFromModel(inputPath string, sourcePath string, outputPath string, names []string, overwrite bool) error {
+	sourceData, err := m.ReadModelFile(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read source model file: %w", err)
+	}
+
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		if len(sourceData.Vertices) != len(modelData.Vertices) {
+			return fmt.Errorf("topology mismatch: source model has %d vertices, target model has %d", len(sourceData.Vertices), len(modelData.Vertices))
+		}
+
+		var morphs []*COM3D2.MorphData
+		if len(names) == 0 {
+			morphs = sourceData.MorphData
+		} else {
+			for _, name := range names {
+				index, err := findMorph(sourceData, name)
+				if err != nil {
+					return err
+				}
+				morphs = append(morphs, sourceData.MorphData[index])
+			}
+		}
+
+		for _, morph := range morphs {
+			copied := cloneMorph(morph)
+			if index, err := findMorph(modelData, morph.Name); err == nil {
+				if !overwrite {
+					return fmt.Errorf("morph %q already exists in target model", morph.Name)
+				}
+				modelData.MorphData[index] = copied
+				continue
+			}
+			modelData.MorphData = append(modelData.MorphData, copied)
+		}
+		return nil
+	})
+}
+
+// ScaleMorph 将形态键的位置和法线偏移乘以 factor
+func (m *ModelService) ScaleMorph(inputPath string, outputPath string, name string, factor float32) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		index, err := findMorph(modelData, name)
+		if err != nil {
+			return err
+		}
+		morph := modelData.MorphData[index]
+		for i := range morph.Vertex {
+			morph.Vertex[i] = vec3Scale(morph.Vertex[i], factor)
+		}
+		for i := range morph.Normals {
+			morph.Normals[i] = vec3Scale(morph.Normals[i], factor)
+		}
+		return nil
+	})
+}
+
+// MirrorMorph 沿 axis（"X"、"Y"、"Z"，默认 "X"）镜像形态键，例如把只作用于左侧的形态键变为作用于右侧
+// 每个受影响的顶点会按位置查找其对称顶点（距离不超过 tolerance），并把镜像后的偏移写到对称顶点上
+// newName 为空时替换原形态键，否则作为新的形态键追加
+func (m *ModelService) MirrorMorph(inputPath string, outputPath string, name string, newName string, axis string, tolerance float32) (result MorphMirrorResult, err error) {
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		index, err := findMorph(modelData, name)
+		if err != nil {
+			return err
+		}
+		if newName != "" && newName != name {
+			if err := checkMorphNameFree(modelData, newName); err != nil {
+				return err
+			}
+		}
+
+		var mirrored *COM3D2.MorphData
+		mirrored, result = mirrorMorph(modelData, modelData.MorphData[index], axis, tolerance)
+		if newName == "" || newName == name {
+			modelData.MorphData[index] = mirrored
+		} else {
+			mirrored.Name = newName
+			modelData.MorphData = append(modelData.MorphData, mirrored)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// mirrorMorph 返回镜像后的形态键副本
+// 镜像位置处的所有顶点（包括 UV、法线接缝处位置相同的拆分顶点）都会写入偏移，避免接缝裂开
+// 多个源顶点对应同一目标顶点时取距离最近的源顶点，目标顶点不会重复
+func mirrorMorph(modelData *COM3D2.Model, morph *COM3D2.MorphData, axis string, tolerance float32) (*COM3D2.MorphData, MorphMirrorResult) {
+	var result MorphMirrorResult
+	if tolerance <= 0 {
+		tolerance = 1e-4
+	}
+	positions := vertexPositions(modelData)
+	grid := newPositionGrid(positions, tolerance)
+
+	// 每个目标顶点对应的源（morph 中的下标）及距离
+	type mirrorSource struct {
+		index    int
+		distance float32
+	}
+	sources := make(map[int]mirrorSource)
+	for i, vertexIndex := range morph.Indices {
+		if int(vertexIndex) >= len(modelData.Vertices) || i >= len(morph.Vertex) {
+			result.UnmatchedVertexCount++
+			continue
+		}
+		mirroredPosition := mirrorVec3(positions[vertexIndex], axis)
+		targets := grid.within(mirroredPosition, tolerance)
+		if len(targets) == 0 {
+			result.UnmatchedVertexCount++
+			continue
+		}
+		for _, target := range targets {
+			distance := vec3Length(vec3Sub(positions[target], mirroredPosition))
+			if existing, ok := sources[target]; !ok || distance < existing.distance {
+				sources[target] = mirrorSource{index: i, distance: distance}
+			}
+		}
+		result.MirroredVertexCount++
+	}
+
+	targets := make([]int, 0, len(sources))
+	for target := range sources {
+		targets = append(targets, target)
+	}
+	slices.Sort(targets)
+
+	mirrored := &COM3D2.MorphData{Name: morph.Name}
+	hasNormals := len(morph.Normals) == len(morph.Indices)
+	hasTangents := len(morph.Tangents) == len(morph.Indices)
+	for _, target := range targets {
+		i := sources[target].index
+		mirrored.Indices = append(mirrored.Indices, target)
+		mirrored.Vertex = append(mirrored.Vertex, mirrorVec3(morph.Vertex[i], axis))
+		if hasNormals {
+			mirrored.Normals = append(mirrored.Normals, mirrorVec3(morph.Normals[i], axis))
+		}
+		if hasTangents {
+			// 切线为方向加符号位，镜像后方向取反分量，符号位翻转
+			t := morph.Tangents[i]
+			d := mirrorVec3(COM3D2.Vector3{X: t.X, Y: t.Y, Z: t.Z}, axis)
+			mirrored.Tangents = append(mirrored.Tangents, COM3D2.Quaternion{X: d.X, Y: d.Y, Z: d.Z, W: -t.W})
+		}
+	}
+	return mirrored, result
+}
+
+// editModel 读取模型，调用 edit 修改后写入 outputPath
+func (m *ModelService) editModel(inputPath string, outputPath string, edit func(modelData *COM3D2.Model) error) error {
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read model file: %w", err)
+	}
+	if err := edit(modelData); err != nil {
+		return err
+	}
+	return m.WriteModelFile(outputPath, modelData)
+}
+
+// findMorph 按名称查找形态键的位置
+func findMorph(modelData *COM3D2.Model, name string) (int, error) {
+	for i, morph := range modelData.MorphData {
+		if morph.Name == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("morph %q not found", name)
+}
+
+// checkMorphNameFree 检查形态键名称是否可用
+func checkMorphNameFree(modelData *COM3D2.Model, name string) error {
+	if name == "" {
+		return fmt.Errorf("morph name cannot be empty")
+	}
+	if _, err := findMorph(modelData, name); err == nil {
+		return fmt.Errorf("morph %q already exists", name)
+	}
+	return nil
+}
+
+// cloneMorph 深拷贝形态键
+func cloneMorph(morph *COM3D2.MorphData) *COM3D2.MorphData {
+	return &COM3D2.MorphData{
+		Name:     morph.Name,
+		Indices:  slices.Clone(morph.Indices),
+		Vertex:   slices.Clone(morph.Vertex),
+		Normals:  slices.Clone(morph.Normals),
+		Tangents: slices.Clone(morph.Tangents),
+	}
+}
@@ -0,0 +1,86 @@
+package COM3D2
+
+import (
+	"fmt"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// MorphBakeWeight 烘焙形态键时使用的名称和权重
+type MorphBakeWeight struct {
+	Name   string  `json:"Name"`   // 形态键名称
+	Weight float32 `json:"Weight"` // 权重，1 表示完全应用
+}
+
+// BakeMorphs 按权重将一个或多个形态键应用到顶点的位置和法线上，并写入 outputPath
+// 骨骼、蒙皮权重、子网格和材质保持不变；removeBaked 为 true 时会从结果中删除已烘焙的形态键
+func (m *ModelService) BakeMorphs(inputPath string, outputPath string, weights []MorphBakeWeight, removeBaked bool) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		return bakeMorphs(modelData, weights, removeBaked)
+	})
+}
+
+// bakeMorphs 就地烘焙形态键
+func bakeMorphs(modelData *COM3D2.Model, weights []MorphBakeWeight, removeBaked bool) error {
+	if len(weights) == 0 {
+		return fmt.Errorf("no morphs to bake")
+	}
+
+	keep := make([]bool, len(modelData.MorphData))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	touched := make([]bool, len(modelData.Vertices))
+	hasTangents := len(modelData.Tangents) == len(modelData.Vertices)
+	for _, bake := range weights {
+		index, err := findMorph(modelData, bake.Name)
+		if err != nil {
+			return err
+		}
+		keep[index] = !removeBaked
+		if bake.Weight == 0 {
+			continue
+		}
+
+		morph := modelData.MorphData[index]
+		for i, vertexIndex := range morph.Indices {
+			v := int(vertexIndex)
+			if v >= len(modelData.Vertices) {
+				return fmt.Errorf("morph %q references vertex %d, but the model only has %d vertices", morph.Name, v, len(modelData.Vertices))
+			}
+			vertex := &modelData.Vertices[v]
+			if i < len(morph.Vertex) {
+				vertex.Position = vec3Add(vertex.Position, vec3Scale(morph.Vertex[i], bake.Weight))
+			}
+			if i < len(morph.Normals) {
+				vertex.Normal = vec3Add(vertex.Normal, vec3Scale(morph.Normals[i], bake.Weight))
+				touched[v] = true
+			}
+			if hasTangents && i < len(morph.Tangents) {
+				t := &modelData.Tangents[v]
+				t.X += morph.Tangents[i].X * bake.Weight
+				t.Y += morph.Tangents[i].Y * bake.Weight
+				t.Z += morph.Tangents[i].Z * bake.Weight
+			}
+		}
+	}
+
+	// 叠加偏移后法线和切线方向需要重新归一化
+	for v, ok := range touched {
+		if !ok {
+			continue
+		}
+		modelData.Vertices[v].Normal = vec3Normalize(modelData.Vertices[v].Normal)
+		if hasTangents {
+			t := &modelData.Tangents[v]
+			d := vec3Normalize(COM3D2.Vector3{X: t.X, Y: t.Y, Z: t.Z})
+			t.X, t.Y, t.Z = d.X, d.Y, d.Z
+		}
+	}
+
+	if removeBaked {
+		modelData.MorphData = filterByMask(modelData.MorphData, keep)
+	}
+	return nil
+}
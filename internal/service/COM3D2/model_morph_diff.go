@@ -0,0 +1,109 @@
+package COM3D2
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// MorphFromDiffResult 由两个模型的差异生成形态键的结果
+type MorphFromDiffResult struct {
+	AffectedVertexCount int     `json:"AffectedVertexCount"` // 写入形态键的顶点数
+	SkippedVertexCount  int     `json:"SkippedVertexCount"`  // 偏移小于 epsilon 而被丢弃的顶点数
+	MaxDisplacement     float32 `json:"MaxDisplacement"`     // 最大位置偏移
+}
+
+// CreateMorphFromModels 计算 variantPath 相对于 basePath 的逐顶点位置和法线偏移，作为名为 morphName 的形态键追加到基础模型上并写入 outputPath
+// 两个模型的顶点数量、顺序和三角形必须完全一致，否则返回错误
+// 位置和法线偏移都小于 epsilon 的顶点会被丢弃
+func (m *ModelService) CreateMorphFromModels(basePath string, variantPath string, outputPath string, morphName string, epsilon float32) (result MorphFromDiffResult, err error) {
+	variantData, err := m.ReadModelFile(variantPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read variant model file: %w", err)
+	}
+
+	err = m.editModel(basePath, outputPath, func(baseData *COM3D2.Model) error {
+		if err := checkMorphNameFree(baseData, morphName); err != nil {
+			return err
+		}
+		var morph *COM3D2.MorphData
+		morph, result, err = morphFromDiff(baseData, variantData, epsilon)
+		if err != nil {
+			return err
+		}
+		morph.Name = morphName
+		baseData.MorphData = append(baseData.MorphData, morph)
+		return nil
+	})
+	return result, err
+}
+
+// morphFromDiff 计算 variant 相对于 base 的形态键
+func morphFromDiff(baseData *COM3D2.Model, variantData *COM3D2.Model, epsilon float32) (*COM3D2.MorphData, MorphFromDiffResult, error) {
+	var result MorphFromDiffResult
+	if err := checkSameTopology(baseData, variantData); err != nil {
+		return nil, result, err
+	}
+	if epsilon < 0 {
+		epsilon = 0
+	}
+
+	withTangents := morphsUseTangents(baseData)
+	canDiffTangents := len(baseData.Tangents) == len(baseData.Vertices) && len(variantData.Tangents) == len(variantData.Vertices)
+
+	morph := &COM3D2.MorphData{}
+	for i := range baseData.Vertices {
+		dp := vec3Sub(variantData.Vertices[i].Position, baseData.Vertices[i].Position)
+		dn := vec3Sub(variantData.Vertices[i].Normal, baseData.Vertices[i].Normal)
+		dpLen, dnLen := vec3Length(dp), vec3Length(dn)
+		if dpLen <= epsilon && dnLen <= epsilon {
+			result.SkippedVertexCount++
+			continue
+		}
+
+		morph.Indices = append(morph.Indices, i)
+		morph.Vertex = append(morph.Vertex, dp)
+		morph.Normals = append(morph.Normals, dn)
+		if withTangents {
+			var dt COM3D2.Quaternion
+			if canDiffTangents {
+				bt, vt := baseData.Tangents[i], variantData.Tangents[i]
+				dt = COM3D2.Quaternion{X: vt.X - bt.X, Y: vt.Y - bt.Y, Z: vt.Z - bt.Z, W: 0}
+			}
+			morph.Tangents = append(morph.Tangents, dt)
+		}
+
+		result.AffectedVertexCount++
+		if dpLen > result.MaxDisplacement {
+			result.MaxDisplacement = dpLen
+		}
+	}
+	return morph, result, nil
+}
+
+// checkSameTopology 检查两个模型是否具有相同的顶点数量和三角形
+func checkSameTopology(a *COM3D2.Model, b *COM3D2.Model) error {
+	if len(a.Vertices) != len(b.Vertices) {
+		return fmt.Errorf("topology mismatch: vertex count %d vs %d", len(a.Vertices), len(b.Vertices))
+	}
+	if len(a.SubMeshes) != len(b.SubMeshes) {
+		return fmt.Errorf("topology mismatch: submesh count %d vs %d", len(a.SubMeshes), len(b.SubMeshes))
+	}
+	for i := range a.SubMeshes {
+		if !slices.Equal(a.SubMeshes[i], b.SubMeshes[i]) {
+			return fmt.Errorf("topology mismatch: triangles of submesh %d differ", i)
+		}
+	}
+	return nil
+}
+
+// morphsUseTangents 判断模型中已有的形态键是否带有切线数据，新建的形态键应与其保持一致
+func morphsUseTangents(modelData *COM3D2.Model) bool {
+	for _, morph := range modelData.MorphData {
+		if len(morph.Tangents) > 0 {
+			return true
+		}
+	}
+	return false
+}
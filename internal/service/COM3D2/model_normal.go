@@ -0,0 +1,278 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// NormalRecalcOptions 重新计算法线和切线的选项
+type NormalRecalcOptions struct {
+	SubMeshIndex      int     `json:"SubMeshIndex"`      // 只处理该子网格，-1 表示整个模型
+	AngleThreshold    float32 `json:"AngleThreshold"`    // 平滑角度阈值（度），面法线夹角超过该值的面不参与平滑，0 表示 180（完全平滑）
+	WeldSeams         bool    `json:"WeldSeams"`         // 是否跨 UV 接缝平滑，即位置相同的重复顶点共享法线
+	RecalcTangents    bool    `json:"RecalcTangents"`    // 是否重新计算切线
+	RecalcMorphNormal bool    `json:"RecalcMorphNormal"` // 是否同时重新计算形态键的法线偏移
+}
+
+// RecalculateNormals 重新计算模型的平滑法线，并可选地重新计算切线和形态键法线，写入 outputPath
+func (m *ModelService) RecalculateNormals(inputPath string, outputPath string, options NormalRecalcOptions) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		return recalculateNormals(modelData, options)
+	})
+}
+
+// recalculateNormals 就地重新计算法线
+func recalculateNormals(modelData *COM3D2.Model, options NormalRecalcOptions) error {
+	triangles, err := collectTriangles(modelData, options.SubMeshIndex)
+	if err != nil {
+		return err
+	}
+
+	basePositions := vertexPositions(modelData)
+	normals, touched := computeVertexNormals(basePositions, triangles, options)
+	for v, ok := range touched {
+		if ok {
+			modelData.Vertices[v].Normal = normals[v]
+		}
+	}
+
+	if options.RecalcTangents {
+		if len(modelData.Tangents) != len(modelData.Vertices) {
+			modelData.Tangents = make([]COM3D2.Quaternion, len(modelData.Vertices))
+		}
+		uvs := make([]COM3D2.Vector2, len(modelData.Vertices))
+		for i := range modelData.Vertices {
+			uvs[i] = modelData.Vertices[i].UV
+		}
+		tangents := computeVertexTangents(basePositions, normals, uvs, triangles)
+		for v, ok := range touched {
+			if ok {
+				modelData.Tangents[v] = tangents[v]
+			}
+		}
+	}
+
+	if options.RecalcMorphNormal {
+		for _, morph := range modelData.MorphData {
+			recalculateMorphNormals(modelData, morph, basePositions, normals, touched, triangles, options)
+		}
+	}
+
+	return nil
+}
+
+// collectTriangles 收集需要处理的三角形，subMeshIndex 为 -1 时收集所有子网格
+func collectTriangles(modelData *COM3D2.Model, subMeshIndex int) ([][3]int, error) {
+	if subMeshIndex >= len(modelData.SubMeshes) || subMeshIndex < -1 {
+		return nil, fmt.Errorf("submesh %d out of range [0, %d)", subMeshIndex, len(modelData.SubMeshes))
+	}
+	var triangles [][3]int
+	for i, subMesh := range modelData.SubMeshes {
+		if subMeshIndex >= 0 && i != subMeshIndex {
+			continue
+		}
+		for t := 0; t+2 < len(subMesh); t += 3 {
+			tri := [3]int{int(subMesh[t]), int(subMesh[t+1]), int(subMesh[t+2])}
+			for _, v := range tri {
+				if v < 0 || v >= len(modelData.Vertices) {
+					return nil, fmt.Errorf("submesh %d references vertex %d, but the model only has %d vertices", i, v, len(modelData.Vertices))
+				}
+			}
+			triangles = append(triangles, tri)
+		}
+	}
+	return triangles, nil
+}
+
+// computeVertexNormals 按角度加权计算顶点法线，返回法线和被三角形引用的顶点标记
+func computeVertexNormals(positions []COM3D2.Vector3, triangles [][3]int, options NormalRecalcOptions) ([]COM3D2.Vector3, []bool) {
+	faceNormals := make([]COM3D2.Vector3, len(triangles))
+	cornerWeights := make([][3]float32, len(triangles))
+	vertexFaces := make([][]int, len(positions))
+	touched := make([]bool, len(positions))
+	for f, tri := range triangles {
+		p0, p1, p2 := positions[tri[0]], positions[tri[1]], positions[tri[2]]
+		faceNormals[f] = vec3Normalize(vec3Cross(vec3Sub(p1, p0), vec3Sub(p2, p0)))
+		cornerWeights[f] = [3]float32{
+			vec3Angle(vec3Sub(p1, p0), vec3Sub(p2, p0)),
+			vec3Angle(vec3Sub(p2, p1), vec3Sub(p0, p1)),
+			vec3Angle(vec3Sub(p0, p2), vec3Sub(p1, p2)),
+		}
+		for _, v := range tri {
+			vertexFaces[v] = append(vertexFaces[v], f)
+			touched[v] = true
+		}
+	}
+
+	// 位置相同的顶点分为一组，用于跨 UV 接缝平滑
+	var samePosition map[COM3D2.Vector3][]int
+	if options.WeldSeams {
+		samePosition = make(map[COM3D2.Vector3][]int)
+		for v, ok := range touched {
+			if ok {
+				samePosition[positions[v]] = append(samePosition[positions[v]], v)
+			}
+		}
+	}
+
+	threshold := options.AngleThreshold
+	if threshold <= 0 || threshold > 180 {
+		threshold = 180
+	}
+	cosThreshold := float32(math.Cos(float64(threshold) * math.Pi / 180))
+
+	normals := make([]COM3D2.Vector3, len(positions))
+	for v, ok := range touched {
+		if !ok {
+			continue
+		}
+		// 以顶点自身所在面的法线作为参考方向，只有与参考方向夹角在阈值内的面才参与平滑
+		var reference COM3D2.Vector3
+		for _, f := range vertexFaces[v] {
+			reference = vec3Add(reference, faceNormals[f])
+		}
+		reference = vec3Normalize(reference)
+
+		candidates := []int{v}
+		if samePosition != nil {
+			candidates = samePosition[positions[v]]
+		}
+		var sum COM3D2.Vector3
+		for _, c := range candidates {
+			for _, f := range vertexFaces[c] {
+				if c != v && vec3Dot(faceNormals[f], reference) < cosThreshold {
+					continue
+				}
+				for corner := 0; corner < 3; corner++ {
+					if triangles[f][corner] == c {
+						sum = vec3Add(sum, vec3Scale(faceNormals[f], cornerWeights[f][corner]))
+					}
+				}
+			}
+		}
+		if vec3Length(sum) == 0 {
+			sum = reference
+		}
+		normals[v] = vec3Normalize(sum)
+	}
+	return normals, touched
+}
+
+// computeVertexTangents 根据 UV 计算切线，W 为副切线方向的符号（1 或 -1），与 Unity 一致
+func computeVertexTangents(positions []COM3D2.Vector3, normals []COM3D2.Vector3, uvs []COM3D2.Vector2, triangles [][3]int) []COM3D2.Quaternion {
+	tan := make([]COM3D2.Vector3, len(positions))
+	bitan := make([]COM3D2.Vector3, len(positions))
+	for _, tri := range triangles {
+		p0, p1, p2 := positions[tri[0]], positions[tri[1]], positions[tri[2]]
+		uv0, uv1, uv2 := uvs[tri[0]], uvs[tri[1]], uvs[tri[2]]
+		e1, e2 := vec3Sub(p1, p0), vec3Sub(p2, p0)
+		du1, dv1 := uv1.X-uv0.X, uv1.Y-uv0.Y
+		du2, dv2 := uv2.X-uv0.X, uv2.Y-uv0.Y
+		det := du1*dv2 - du2*dv1
+		if det == 0 {
+			continue
+		}
+		r := 1 / det
+		sdir := vec3Scale(vec3Sub(vec3Scale(e1, dv2), vec3Scale(e2, dv1)), r)
+		tdir := vec3Scale(vec3Sub(vec3Scale(e2, du1), vec3Scale(e1, du2)), r)
+		for _, v := range tri {
+			tan[v] = vec3Add(tan[v], sdir)
+			bitan[v] = vec3Add(bitan[v], tdir)
+		}
+	}
+
+	tangents := make([]COM3D2.Quaternion, len(positions))
+	for v := range positions {
+		n := normals[v]
+		// Gram-Schmidt 正交化
+		t := vec3Normalize(vec3Sub(tan[v], vec3Scale(n, vec3Dot(n, tan[v]))))
+		if vec3Length(t) == 0 {
+			t = anyPerpendicular(n)
+		}
+		w := float32(1)
+		if vec3Dot(vec3Cross(n, t), bitan[v]) < 0 {
+			w = -1
+		}
+		tangents[v] = COM3D2.Quaternion{X: t.X, Y: t.Y, Z: t.Z, W: w}
+	}
+	return tangents
+}
+
+// recalculateMorphNormals 对形态键完全应用后的网格重新计算法线，并以与基础法线的差作为法线偏移
+// 受影响的顶点可能会比原来多（相邻顶点的法线也会变化），新增顶点的位置偏移为 0
+func recalculateMorphNormals(modelData *COM3D2.Model, morph *COM3D2.MorphData, basePositions []COM3D2.Vector3, baseNormals []COM3D2.Vector3, touched []bool, triangles [][3]int, options NormalRecalcOptions) {
+	type morphEntry struct {
+		delta   COM3D2.Vector3
+		tangent COM3D2.Quaternion
+	}
+	entries := make(map[int]morphEntry, len(morph.Indices))
+	morphed := make([]COM3D2.Vector3, len(basePositions))
+	copy(morphed, basePositions)
+	for i, vertexIndex := range morph.Indices {
+		v := int(vertexIndex)
+		if v >= len(morphed) || i >= len(morph.Vertex) {
+			continue
+		}
+		entry := morphEntry{delta: morph.Vertex[i]}
+		if i < len(morph.Tangents) {
+			entry.tangent = morph.Tangents[i]
+		}
+		entries[v] = entry
+		morphed[v] = vec3Add(morphed[v], morph.Vertex[i])
+	}
+
+	morphedNormals, _ := computeVertexNormals(morphed, triangles, options)
+
+	normalDeltas := make(map[int]COM3D2.Vector3, len(entries))
+	for v, ok := range touched {
+		if !ok {
+			continue
+		}
+		d := vec3Sub(morphedNormals[v], baseNormals[v])
+		if _, inMorph := entries[v]; inMorph || vec3Length(d) > 1e-5 {
+			normalDeltas[v] = d
+		}
+	}
+	// 不在处理范围内的顶点保留原有的法线偏移
+	for i, vertexIndex := range morph.Indices {
+		v := int(vertexIndex)
+		if _, ok := normalDeltas[v]; !ok && i < len(morph.Normals) {
+			normalDeltas[v] = morph.Normals[i]
+		}
+	}
+
+	indices := make([]int, 0, len(normalDeltas))
+	for v := range normalDeltas {
+		indices = append(indices, v)
+	}
+	sort.Ints(indices)
+
+	withTangents := len(morph.Tangents) > 0
+	morph.Indices = morph.Indices[:0]
+	morph.Vertex = morph.Vertex[:0]
+	morph.Normals = morph.Normals[:0]
+	if withTangents {
+		morph.Tangents = morph.Tangents[:0]
+	}
+	for _, v := range indices {
+		entry := entries[v]
+		morph.Indices = append(morph.Indices, v)
+		morph.Vertex = append(morph.Vertex, entry.delta)
+		morph.Normals = append(morph.Normals, normalDeltas[v])
+		if withTangents {
+			morph.Tangents = append(morph.Tangents, entry.tangent)
+		}
+	}
+}
+
+// anyPerpendicular 返回与 n 垂直的任意单位向量
+func anyPerpendicular(n COM3D2.Vector3) COM3D2.Vector3 {
+	axis := COM3D2.Vector3{X: 1}
+	if math.Abs(float64(n.X)) > 0.9 {
+		axis = COM3D2.Vector3{Y: 1}
+	}
+	return vec3Normalize(vec3Cross(n, axis))
+}
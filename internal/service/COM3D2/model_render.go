@@ -0,0 +1,536 @@
+package COM3D2
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"github.com/emmansun/base64" // use faster base64 implementation
+)
+
+// 渲染相关的默认值
+const (
+	defaultRenderSize  = 256  // 预览图默认边长
+	defaultIconSize    = 80   // 游戏内 .menu 图标的边长
+	maxRenderSize      = 4096 // 允许的最大边长
+	renderSupersample  = 2    // 超采样倍数，用于抗锯齿
+	renderAlphaCutoff  = 0.5  // 贴图透明度低于该值的像素不绘制
+	renderAmbientLight = 0.45 // 环境光强度
+)
+
+// ModelRenderOptions 模型预览渲染选项
+type ModelRenderOptions struct {
+	Width       int      `json:"Width"`       // 图片宽度，0 时使用默认值
+	Height      int      `json:"Height"`      // 图片高度，0 时使用默认值
+	Yaw         float32  `json:"Yaw"`         // 相机绕 Y 轴旋转的角度（度），0 为正面
+	Pitch       float32  `json:"Pitch"`       // 相机俯仰角（度），正值为从上往下看
+	Zoom        float32  `json:"Zoom"`        // 缩放倍数，0 时视为 1，即模型刚好填满画面
+	MorphName   string   `json:"MorphName"`   // 应用的形态键，为空时不应用
+	MorphWeight float32  `json:"MorphWeight"` // 形态键权重
+	TextureDirs []string `json:"TextureDirs"` // 查找 .tex 的目录（包括子目录），模型所在目录总是会被查找
+	Background  [4]uint8 `json:"Background"`  // 背景颜色 RGBA，默认为透明
+}
+
+// ModelRenderResult 模型预览渲染结果
+type ModelRenderResult struct {
+	Base64EncodedPngData string   `json:"Base64EncodedPngData"` // base64 编码的 PNG 数据
+	MissingTextures      []string `json:"MissingTextures"`      // 找不到或无法解码的贴图，这些材质会以纯色渲染；PNG/JPG/DXT 以外格式的贴图需要 ImageMagick 才能解码
+}
+
+// RenderModelPreview 以静止姿态渲染模型预览图，返回 base64 编码的 PNG 数据
+func (m *ModelService) RenderModelPreview(inputPath string, options ModelRenderOptions) (result ModelRenderResult, err error) {
+	img, missing, err := m.renderModelFile(inputPath, options, defaultRenderSize)
+	if err != nil {
+		return result, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return result, fmt.Errorf("failed to encode png: %w", err)
+	}
+	result.Base64EncodedPngData = base64.StdEncoding.EncodeToString(buf.Bytes())
+	result.MissingTextures = missing
+	return result, nil
+}
+
+// RenderModelPreviewAndWrite 以静止姿态渲染模型预览图，并写出为 PNG 文件
+func (m *ModelService) RenderModelPreviewAndWrite(inputPath string, outputPath string, options ModelRenderOptions) (missingTextures []string, err error) {
+	img, missing, err := m.renderModelFile(inputPath, options, defaultRenderSize)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create png file: %w", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return missing, nil
+}
+
+// RenderModelIconAndWrite 渲染模型并保存为 .menu 可用的图标 .tex 文件
+// 尺寸默认为游戏图标的 80x80，数据位为 PNG，texName 为写入 .tex 的贴图名
+func (m *ModelService) RenderModelIconAndWrite(inputPath string, outputPath string, texName string, options ModelRenderOptions) (missingTextures []string, err error) {
+	img, missing, err := m.renderModelFile(inputPath, options, defaultIconSize)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	if texName == "" {
+		texName = strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath)) + ".png"
+	}
+	tex := &COM3D2.Tex{
+		Signature:     COM3D2.TexSignature,
+		Version:       1010,
+		TextureName:   texName,
+		Width:         int32(img.Bounds().Dx()),
+		Height:        int32(img.Bounds().Dy()),
+		TextureFormat: 5, // ARGB32，数据位为 PNG
+		Data:          buf.Bytes(),
+	}
+	texService := &TexService{}
+	if err := texService.WriteTexFile(outputPath, tex); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+// renderModelFile 读取模型及其贴图并渲染
+func (m *ModelService) renderModelFile(inputPath string, options ModelRenderOptions, defaultSize int) (*image.NRGBA, []string, error) {
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read model file: %w", err)
+	}
+	if options.Width <= 0 {
+		options.Width = defaultSize
+	}
+	if options.Height <= 0 {
+		options.Height = defaultSize
+	}
+	if options.Width > maxRenderSize || options.Height > maxRenderSize {
+		return nil, nil, fmt.Errorf("render size %dx%d exceeds the maximum of %d", options.Width, options.Height, maxRenderSize)
+	}
+
+	textures, missing := loadMaterialTextures(modelData, append([]string{filepath.Dir(inputPath)}, options.TextureDirs...))
+	img, err := renderModel(modelData, textures, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return img, missing, nil
+}
+
+// renderMaterial 渲染时使用的材质信息
+type renderMaterial struct {
+	texture image.Image
+	offset  [2]float32
+	scale   [2]float32
+	color   [4]float32
+}
+
+// loadMaterialTextures 按子网格顺序加载各材质的 _MainTex 和 _Color，返回材质和找不到的贴图名
+func loadMaterialTextures(modelData *COM3D2.Model, dirs []string) ([]renderMaterial, []string) {
+	texService := &TexService{}
+	var index map[string]string
+	var missing []string
+	cache := make(map[string]image.Image)
+
+	materials := make([]renderMaterial, len(modelData.Materials))
+	for i, material := range modelData.Materials {
+		materials[i] = renderMaterial{scale: [2]float32{1, 1}, color: [4]float32{1, 1, 1, 1}}
+		if material == nil {
+			continue
+		}
+		for _, property := range material.Properties {
+			switch p := property.(type) {
+			case *COM3D2.ColProperty:
+				if p.PropName == "_Color" {
+					materials[i].color = p.Color
+				}
+			case *COM3D2.TexProperty:
+				if p.PropName != "_MainTex" || p.Tex2D == nil || p.Tex2D.Path == "" {
+					continue
+				}
+				materials[i].offset = p.Tex2D.Offset
+				materials[i].scale = p.Tex2D.Scale
+				name := strings.ToLower(strings.TrimSuffix(filepath.Base(filepath.ToSlash(p.Tex2D.Path)), filepath.Ext(p.Tex2D.Path)) + ".tex")
+				if img, ok := cache[name]; ok {
+					materials[i].texture = img
+					continue
+				}
+				if index == nil {
+					index = indexTexFiles(dirs)
+				}
+				img, err := decodeTexFile(texService, index[name])
+				if err != nil {
+					missing = append(missing, name)
+				}
+				cache[name] = img
+				materials[i].texture = img
+			}
+		}
+	}
+	return materials, missing
+}
+
+// indexTexFiles 建立小写文件名到 .tex 路径的索引，第一个目录为模型所在目录，只查找其本身，其余目录包括子目录
+// 每个目录的索引会缓存一段时间，批量渲染时不会重复遍历同一个目录树
+func indexTexFiles(dirs []string) map[string]string {
+	index := make(map[string]string)
+	for i, dir := range dirs {
+		for name, path := range cachedTexDirIndex(dir, i > 0) {
+			if _, ok := index[name]; !ok {
+				index[name] = path
+			}
+		}
+	}
+	return index
+}
+
+// texDirIndexTTL 目录的 .tex 索引的缓存时长，超过后重新遍历以发现新增的贴图
+const texDirIndexTTL = time.Minute
+
+// texDirIndexEntry 单个目录的 .tex 索引
+type texDirIndexEntry struct {
+	index   map[string]string
+	builtAt time.Time
+}
+
+// texDirIndexCache 按目录和是否包括子目录缓存的 .tex 索引
+var texDirIndexCache = struct {
+	sync.Mutex
+	entries map[string]*texDirIndexEntry
+}{entries: make(map[string]*texDirIndexEntry)}
+
+// cachedTexDirIndex 返回目录的 .tex 索引，缓存过期或不存在时重新建立
+func cachedTexDirIndex(dir string, recursive bool) map[string]string {
+	key := filepath.Clean(dir)
+	if recursive {
+		key += string(filepath.Separator) + "**"
+	}
+	texDirIndexCache.Lock()
+	entry := texDirIndexCache.entries[key]
+	texDirIndexCache.Unlock()
+	if entry != nil && time.Since(entry.builtAt) < texDirIndexTTL {
+		return entry.index
+	}
+
+	entry = &texDirIndexEntry{index: buildTexDirIndex(dir, recursive), builtAt: time.Now()}
+	texDirIndexCache.Lock()
+	for k, e := range texDirIndexCache.entries {
+		if time.Since(e.builtAt) >= texDirIndexTTL {
+			delete(texDirIndexCache.entries, k)
+		}
+	}
+	texDirIndexCache.entries[key] = entry
+	texDirIndexCache.Unlock()
+	return entry.index
+}
+
+// buildTexDirIndex 遍历目录建立小写文件名到 .tex 路径的索引
+func buildTexDirIndex(dir string, recursive bool) map[string]string {
+	index := make(map[string]string)
+	add := func(path string) {
+		name := strings.ToLower(filepath.Base(path))
+		if _, ok := index[name]; !ok && strings.HasSuffix(name, ".tex") {
+			index[name] = path
+		}
+	}
+	if !recursive {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return index
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				add(filepath.Join(dir, entry.Name()))
+			}
+		}
+		return index
+	}
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			add(path)
+		}
+		return nil
+	})
+	return index
+}
+
+// decodeTexFile 读取 .tex 并解码为图像
+// 数据位为 PNG/JPG 以及 DXT1/DXT5 格式时直接解码，其他格式需要通过 ImageMagick 转换，
+// 未安装 ImageMagick 时这些贴图会解码失败并出现在 MissingTextures 中
+func decodeTexFile(texService *TexService, path string) (image.Image, error) {
+	if path == "" {
+		return nil, fmt.Errorf("tex file not found")
+	}
+	tex, err := texService.ReadTexFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if img, ok, err := decodeTexNative(tex); ok {
+		return img, err
+	}
+	imageData, _, _, err := COM3D2.ConvertTexToImage(tex, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert tex to image (requires ImageMagick): %w", err)
+	}
+	img, err := png.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode png: %w", err)
+	}
+	return img, nil
+}
+
+// renderModel 使用 CPU 光栅化渲染模型，采用正交投影，并以超采样抗锯齿
+func renderModel(modelData *COM3D2.Model, materials []renderMaterial, options ModelRenderOptions) (*image.NRGBA, error) {
+	positions := vertexPositions(modelData)
+	normals := make([]COM3D2.Vector3, len(modelData.Vertices))
+	for i := range modelData.Vertices {
+		normals[i] = modelData.Vertices[i].Normal
+	}
+	if options.MorphName != "" {
+		morphIndex, err := findMorph(modelData, options.MorphName)
+		if err != nil {
+			return nil, err
+		}
+		morph := modelData.MorphData[morphIndex]
+		for i, vertexIndex := range morph.Indices {
+			v := int(vertexIndex)
+			if v >= len(positions) {
+				continue
+			}
+			if i < len(morph.Vertex) {
+				positions[v] = vec3Add(positions[v], vec3Scale(morph.Vertex[i], options.MorphWeight))
+			}
+			if i < len(morph.Normals) {
+				normals[v] = vec3Add(normals[v], vec3Scale(morph.Normals[i], options.MorphWeight))
+			}
+		}
+	}
+	skinRestPose(modelData, positions, normals)
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("model has no vertices")
+	}
+
+	// 视图变换：先绕 Y 轴旋转再俯仰，相机位于 +Z 方向看向 -Z（Unity 中角色面向 +Z）
+	view := quatMul(
+		quatFromAxisAngle(COM3D2.Vector3{X: 1}, float64(options.Pitch)*math.Pi/180),
+		quatFromAxisAngle(COM3D2.Vector3{Y: 1}, float64(options.Yaw)*math.Pi/180),
+	)
+	minP, maxP := positions[0], positions[0]
+	for _, p := range positions {
+		minP = COM3D2.Vector3{X: min(minP.X, p.X), Y: min(minP.Y, p.Y), Z: min(minP.Z, p.Z)}
+		maxP = COM3D2.Vector3{X: max(maxP.X, p.X), Y: max(maxP.Y, p.Y), Z: max(maxP.Z, p.Z)}
+	}
+	center := vec3Scale(vec3Add(minP, maxP), 0.5)
+	radius := vec3Length(vec3Sub(maxP, center))
+	if radius == 0 {
+		radius = 1
+	}
+	zoom := options.Zoom
+	if zoom <= 0 {
+		zoom = 1
+	}
+
+	width, height := options.Width*renderSupersample, options.Height*renderSupersample
+	pixelScale := float32(min(width, height)) / 2 / radius * zoom
+	screen := make([]COM3D2.Vector3, len(positions))
+	viewNormals := make([]COM3D2.Vector3, len(normals))
+	for i, p := range positions {
+		v := quatRotate(view, vec3Sub(p, center))
+		// 左手坐标系中看向 -Z 时屏幕右方为 -X
+		screen[i] = COM3D2.Vector3{
+			X: float32(width)/2 - v.X*pixelScale,
+			Y: float32(height)/2 - v.Y*pixelScale,
+			Z: v.Z,
+		}
+		viewNormals[i] = vec3Normalize(quatRotate(view, normals[i]))
+	}
+
+	colorBuffer := make([]color.NRGBA, width*height)
+	depthBuffer := make([]float32, width*height)
+	for i := range depthBuffer {
+		depthBuffer[i] = float32(math.Inf(-1))
+	}
+	light := vec3Normalize(COM3D2.Vector3{X: 0.3, Y: 0.5, Z: 1})
+
+	for subMeshIndex, subMesh := range modelData.SubMeshes {
+		material := renderMaterial{scale: [2]float32{1, 1}, color: [4]float32{0.8, 0.8, 0.8, 1}}
+		if subMeshIndex < len(materials) {
+			material = materials[subMeshIndex]
+		}
+		for t := 0; t+2 < len(subMesh); t += 3 {
+			tri := [3]int{int(subMesh[t]), int(subMesh[t+1]), int(subMesh[t+2])}
+			if tri[0] >= len(screen) || tri[1] >= len(screen) || tri[2] >= len(screen) || tri[0] < 0 || tri[1] < 0 || tri[2] < 0 {
+				continue
+			}
+			rasterizeTriangle(modelData, tri, screen, viewNormals, material, light, width, height, colorBuffer, depthBuffer)
+		}
+	}
+
+	return downsample(colorBuffer, depthBuffer, width, options), nil
+}
+
+// rasterizeTriangle 光栅化单个三角形，不做背面剔除（服装多为双面材质）
+func rasterizeTriangle(modelData *COM3D2.Model, tri [3]int, screen []COM3D2.Vector3, normals []COM3D2.Vector3, material renderMaterial, light COM3D2.Vector3, width, height int, colorBuffer []color.NRGBA, depthBuffer []float32) {
+	a, b, c := screen[tri[0]], screen[tri[1]], screen[tri[2]]
+	area := (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	if area == 0 {
+		return
+	}
+	minX := max(int(math.Floor(float64(min(a.X, b.X, c.X)))), 0)
+	maxX := min(int(math.Ceil(float64(max(a.X, b.X, c.X)))), width-1)
+	minY := max(int(math.Floor(float64(min(a.Y, b.Y, c.Y)))), 0)
+	maxY := min(int(math.Ceil(float64(max(a.Y, b.Y, c.Y)))), height-1)
+
+	uv0, uv1, uv2 := modelData.Vertices[tri[0]].UV, modelData.Vertices[tri[1]].UV, modelData.Vertices[tri[2]].UV
+	n0, n1, n2 := normals[tri[0]], normals[tri[1]], normals[tri[2]]
+
+	for y := minY; y <= maxY; y++ {
+		py := float32(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float32(x) + 0.5
+			w0 := ((b.X-px)*(c.Y-py) - (b.Y-py)*(c.X-px)) / area
+			w1 := ((c.X-px)*(a.Y-py) - (c.Y-py)*(a.X-px)) / area
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+			depth := w0*a.Z + w1*b.Z + w2*c.Z
+			pixel := y*width + x
+			if depth <= depthBuffer[pixel] {
+				continue
+			}
+
+			r, g, bl, al := material.color[0], material.color[1], material.color[2], material.color[3]
+			if material.texture != nil {
+				u := (w0*uv0.X+w1*uv1.X+w2*uv2.X)*material.scale[0] + material.offset[0]
+				v := (w0*uv0.Y+w1*uv1.Y+w2*uv2.Y)*material.scale[1] + material.offset[1]
+				tr, tg, tb, ta := sampleTexture(material.texture, u, v)
+				r, g, bl, al = r*tr, g*tg, bl*tb, al*ta
+			}
+			if al < renderAlphaCutoff {
+				continue
+			}
+
+			n := vec3Normalize(vec3Add(vec3Add(vec3Scale(n0, w0), vec3Scale(n1, w1)), vec3Scale(n2, w2)))
+			diffuse := float32(math.Abs(float64(vec3Dot(n, light))))
+			shade := renderAmbientLight + (1-renderAmbientLight)*diffuse
+
+			depthBuffer[pixel] = depth
+			colorBuffer[pixel] = color.NRGBA{
+				R: toColorByte(r * shade),
+				G: toColorByte(g * shade),
+				B: toColorByte(bl * shade),
+				A: 255,
+			}
+		}
+	}
+}
+
+// sampleTexture 以重复寻址方式对贴图进行最近点采样，UV 原点在左下角
+func sampleTexture(img image.Image, u, v float32) (r, g, b, a float32) {
+	bounds := img.Bounds()
+	u -= float32(math.Floor(float64(u)))
+	v -= float32(math.Floor(float64(v)))
+	x := bounds.Min.X + min(int(u*float32(bounds.Dx())), bounds.Dx()-1)
+	y := bounds.Min.Y + min(int((1-v)*float32(bounds.Dy())), bounds.Dy()-1)
+	c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	return float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255, float32(c.A) / 255
+}
+
+// downsample 将超采样的缓冲区缩小为最终尺寸，未绘制的像素使用背景色
+func downsample(colorBuffer []color.NRGBA, depthBuffer []float32, width int, options ModelRenderOptions) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, options.Width, options.Height))
+	background := options.Background
+	samples := float32(renderSupersample * renderSupersample)
+	for y := 0; y < options.Height; y++ {
+		for x := 0; x < options.Width; x++ {
+			var r, g, b, a float32
+			for sy := 0; sy < renderSupersample; sy++ {
+				for sx := 0; sx < renderSupersample; sx++ {
+					pixel := (y*renderSupersample+sy)*width + x*renderSupersample + sx
+					c := colorBuffer[pixel]
+					if math.IsInf(float64(depthBuffer[pixel]), -1) {
+						c = color.NRGBA{R: background[0], G: background[1], B: background[2], A: background[3]}
+					}
+					// 按透明度加权平均，避免透明背景的颜色渗入边缘
+					alpha := float32(c.A) / 255
+					r += float32(c.R) * alpha
+					g += float32(c.G) * alpha
+					b += float32(c.B) * alpha
+					a += alpha
+				}
+			}
+			if a == 0 {
+				continue
+			}
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / a),
+				G: uint8(g / a),
+				B: uint8(b / a),
+				A: toColorByte(a / samples),
+			})
+		}
+	}
+	return img
+}
+
+// toColorByte 将 [0, 1] 的颜色分量转换为字节
+func toColorByte(v float32) uint8 {
+	return uint8(min(max(v, 0), 1)*255 + 0.5)
+}
+
+// skinRestPose 按绑定姿态对顶点和法线进行蒙皮，得到骨骼静止姿态下的位置
+// 很多模型的网格空间与骨骼空间的坐标轴不同，蒙皮后才是游戏中看到的朝向
+func skinRestPose(modelData *COM3D2.Model, positions []COM3D2.Vector3, normals []COM3D2.Vector3) {
+	if len(modelData.BoneWeights) != len(positions) || len(modelData.BindPoses) == 0 {
+		return
+	}
+	skin := skinMatrices(modelData)
+	for i := range positions {
+		influences := getBoneInfluences(modelData.BoneWeights[i])
+		var p, n COM3D2.Vector3
+		var total float32
+		for _, inf := range influences {
+			if inf.Weight <= 0 || inf.Index >= len(skin) || skin[inf.Index] == nil {
+				continue
+			}
+			p = vec3Add(p, vec3Scale(skin[inf.Index].mulPoint(positions[i]), inf.Weight))
+			n = vec3Add(n, vec3Scale(skin[inf.Index].mulVector(normals[i]), inf.Weight))
+			total += inf.Weight
+		}
+		if total > 0 {
+			positions[i] = vec3Scale(p, 1/total)
+			normals[i] = vec3Normalize(n)
+		}
+	}
+}
+
+// skinMatrices 返回每个蒙皮骨骼（BoneNames 的下标）的蒙皮矩阵：骨骼世界矩阵 * BindPose，找不到骨骼时为 nil
+func skinMatrices(modelData *COM3D2.Model) []*mat4 {
+	boneWorld := boneWorldMatrices(modelData.Bones)
+	skin := make([]*mat4, len(modelData.BoneNames))
+	for skinIndex, name := range modelData.BoneNames {
+		boneIndex := findBone(modelData, name)
+		if boneIndex < 0 || skinIndex >= len(modelData.BindPoses) {
+			continue
+		}
+		m := mat4Mul(boneWorld[boneIndex], mat4FromMatrix4x4(modelData.BindPoses[skinIndex]))
+		skin[skinIndex] = &m
+	}
+	return skin
+}
@@ -0,0 +1,234 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 骨架比较的默认容差
+const (
+	defaultSkeletonPositionTolerance = 0.001 // 位置容差，单位与模型坐标一致
+	defaultSkeletonAngleTolerance    = 1     // 角度容差（度）
+)
+
+// SkeletonReference 参考骨架，可以从 .model 导出为 JSON，方便在没有身体模型时进行比较
+type SkeletonReference struct {
+	Name      string             `json:"Name"`      // 来源模型名
+	Bones     []*COM3D2.Bone     `json:"Bones"`     // 骨骼层级
+	BoneNames []string           `json:"BoneNames"` // 蒙皮骨骼
+	BindPoses []COM3D2.Matrix4x4 `json:"BindPoses"` // 与 BoneNames 一一对应
+}
+
+// SkeletonCompareOptions 骨架比较选项
+type SkeletonCompareOptions struct {
+	PositionTolerance float32 `json:"PositionTolerance"` // BindPose 位置差超过该值时报告，0 时使用默认值 0.001
+	AngleTolerance    float32 `json:"AngleTolerance"`    // BindPose 旋转差超过该角度（度）时报告，0 时使用默认值 1
+}
+
+// SkeletonParentMismatch 父骨骼不一致的骨骼
+type SkeletonParentMismatch struct {
+	Bone            string `json:"Bone"`            // 骨骼名
+	Parent          string `json:"Parent"`          // 在模型中的父骨骼，根骨骼为空
+	ReferenceParent string `json:"ReferenceParent"` // 在参考骨架中的父骨骼，根骨骼为空
+}
+
+// SkeletonBindPoseDifference BindPose 差异过大的蒙皮骨骼
+type SkeletonBindPoseDifference struct {
+	Bone             string  `json:"Bone"`             // 骨骼名
+	PositionDistance float32 `json:"PositionDistance"` // 骨骼在网格空间中的位置差
+	AngleDegree      float32 `json:"AngleDegree"`      // 骨骼在网格空间中的旋转差（度）
+}
+
+// SkeletonCompareResult 骨架比较结果
+type SkeletonCompareResult struct {
+	Compatible          bool                         `json:"Compatible"`          // 所有蒙皮骨骼都存在于参考骨架中，且父骨骼和 BindPose 一致
+	MissingSkinBones    []string                     `json:"MissingSkinBones"`    // 模型的蒙皮骨骼中参考骨架没有的骨骼，这些骨骼不会跟随身体动画
+	ExtraBones          []string                     `json:"ExtraBones"`          // 模型中参考骨架没有的非蒙皮骨骼
+	MissingBones        []string                     `json:"MissingBones"`        // 参考骨架中模型没有的骨骼，饰品模型通常只包含部分骨骼，仅供参考
+	ParentMismatches    []SkeletonParentMismatch     `json:"ParentMismatches"`    // 两者都有但父骨骼不同的骨骼
+	BindPoseDifferences []SkeletonBindPoseDifference `json:"BindPoseDifferences"` // 两者都是蒙皮骨骼但 BindPose 差异过大的骨骼
+}
+
+// CompareSkeleton 比较 .model 的骨架与参考骨架
+// referencePath 可以是 .model、.model.json，或由 ExportSkeletonReference 导出的骨架 JSON，一般使用游戏的身体模型（例如 body001.model）
+func (m *ModelService) CompareSkeleton(inputPath string, referencePath string, options SkeletonCompareOptions) (result SkeletonCompareResult, err error) {
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+	reference, err := m.loadSkeletonReference(referencePath)
+	if err != nil {
+		return result, err
+	}
+	return compareSkeleton(modelData, reference, options), nil
+}
+
+// ExportSkeletonReference 将 .model 的骨架导出为 JSON，可作为 CompareSkeleton 的参考骨架
+func (m *ModelService) ExportSkeletonReference(inputPath string, outputPath string) error {
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read model file: %w", err)
+	}
+	reference := SkeletonReference{
+		Name:      modelData.Name,
+		Bones:     modelData.Bones,
+		BoneNames: modelData.BoneNames,
+		BindPoses: modelData.BindPoses,
+	}
+	marshal, err := json.Marshal(reference)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputPath, marshal, 0644); err != nil {
+		return fmt.Errorf("failed to write skeleton json file: %w", err)
+	}
+	return nil
+}
+
+// loadSkeletonReference 从 .model、.model.json 或骨架 JSON 中读取参考骨架
+func (m *ModelService) loadSkeletonReference(path string) (*SkeletonReference, error) {
+	if path == "" {
+		return nil, fmt.Errorf("reference skeleton path cannot be empty")
+	}
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".json") && !strings.HasSuffix(lower, ".model.json") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open skeleton json file: %w", err)
+		}
+		reference := &SkeletonReference{}
+		if err := json.Unmarshal(data, reference); err != nil {
+			return nil, fmt.Errorf("failed to read skeleton json file: %w", err)
+		}
+		return reference, nil
+	}
+
+	modelData, err := m.ReadModelFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reference model file: %w", err)
+	}
+	return &SkeletonReference{
+		Name:      modelData.Name,
+		Bones:     modelData.Bones,
+		BoneNames: modelData.BoneNames,
+		BindPoses: modelData.BindPoses,
+	}, nil
+}
+
+// compareSkeleton 比较模型骨架与参考骨架
+func compareSkeleton(modelData *COM3D2.Model, reference *SkeletonReference, options SkeletonCompareOptions) SkeletonCompareResult {
+	positionTolerance := options.PositionTolerance
+	if positionTolerance <= 0 {
+		positionTolerance = defaultSkeletonPositionTolerance
+	}
+	angleTolerance := options.AngleTolerance
+	if angleTolerance <= 0 {
+		angleTolerance = defaultSkeletonAngleTolerance
+	}
+
+	result := SkeletonCompareResult{
+		MissingSkinBones:    []string{},
+		ExtraBones:          []string{},
+		MissingBones:        []string{},
+		ParentMismatches:    []SkeletonParentMismatch{},
+		BindPoseDifferences: []SkeletonBindPoseDifference{},
+	}
+
+	modelParents := boneParentNames(modelData.Bones)
+	referenceParents := boneParentNames(reference.Bones)
+
+	for _, name := range modelData.BoneNames {
+		if _, ok := referenceParents[name]; !ok {
+			result.MissingSkinBones = append(result.MissingSkinBones, name)
+		}
+	}
+	for _, bone := range modelData.Bones {
+		referenceParent, ok := referenceParents[bone.Name]
+		if !ok {
+			if !slices.Contains(modelData.BoneNames, bone.Name) {
+				result.ExtraBones = append(result.ExtraBones, bone.Name)
+			}
+			continue
+		}
+		if parent := modelParents[bone.Name]; parent != referenceParent {
+			result.ParentMismatches = append(result.ParentMismatches, SkeletonParentMismatch{
+				Bone:            bone.Name,
+				Parent:          parent,
+				ReferenceParent: referenceParent,
+			})
+		}
+	}
+	for _, bone := range reference.Bones {
+		if _, ok := modelParents[bone.Name]; !ok {
+			result.MissingBones = append(result.MissingBones, bone.Name)
+		}
+	}
+
+	for skinIndex, name := range modelData.BoneNames {
+		referenceIndex := slices.Index(reference.BoneNames, name)
+		if referenceIndex < 0 || referenceIndex >= len(reference.BindPoses) || skinIndex >= len(modelData.BindPoses) {
+			continue
+		}
+		distance, angle, ok := bindPoseDifference(modelData.BindPoses[skinIndex], reference.BindPoses[referenceIndex])
+		if !ok || distance > positionTolerance || angle > angleTolerance {
+			result.BindPoseDifferences = append(result.BindPoseDifferences, SkeletonBindPoseDifference{
+				Bone:             name,
+				PositionDistance: distance,
+				AngleDegree:      angle,
+			})
+		}
+	}
+
+	result.Compatible = len(result.MissingSkinBones) == 0 && len(result.ParentMismatches) == 0 && len(result.BindPoseDifferences) == 0
+	return result
+}
+
+// boneParentNames 返回骨骼名到父骨骼名的映射，根骨骼的父骨骼为空字符串
+func boneParentNames(bones []*COM3D2.Bone) map[string]string {
+	parents := make(map[string]string, len(bones))
+	for i, bone := range bones {
+		parent := ""
+		if p := int(bone.ParentIndex); p >= 0 && p < len(bones) && p != i {
+			parent = bones[p].Name
+		}
+		parents[bone.Name] = parent
+	}
+	return parents
+}
+
+// bindPoseDifference 比较两个 BindPose 对应的骨骼在网格空间中的位置和旋转差异
+// BindPose 不可逆时第三个返回值为 false
+func bindPoseDifference(a COM3D2.Matrix4x4, b COM3D2.Matrix4x4) (float32, float32, bool) {
+	ia, okA := mat4FromMatrix4x4(a).inverse()
+	ib, okB := mat4FromMatrix4x4(b).inverse()
+	if !okA || !okB {
+		return 0, 0, false
+	}
+	distance := vec3Length(vec3Sub(ia.translation(), ib.translation()))
+
+	// 去掉缩放后比较旋转部分：angle = acos((trace(Ra^T * Rb) - 1) / 2)
+	var trace float64
+	for col := 0; col < 3; col++ {
+		var ca, cb [3]float64
+		var la, lb float64
+		for row := 0; row < 3; row++ {
+			ca[row], cb[row] = ia.at(row, col), ib.at(row, col)
+			la += ca[row] * ca[row]
+			lb += cb[row] * cb[row]
+		}
+		if la == 0 || lb == 0 {
+			return distance, 0, false
+		}
+		for row := 0; row < 3; row++ {
+			trace += ca[row] * cb[row] / math.Sqrt(la*lb)
+		}
+	}
+	cos := math.Max(-1, math.Min(1, (trace-1)/2))
+	return distance, float32(math.Acos(cos) * 180 / math.Pi), true
+}
@@ -0,0 +1,295 @@
+package COM3D2
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 分页读取的缓存限制
+const (
+	maxModelStreamCacheBytes = 512 * 1024 * 1024 // 缓存模型的总大小上限（按文件大小估算），超出时释放最久未使用的其他模型
+	modelStreamIdleTimeout   = 2 * time.Minute   // 模型超过这段时间没有被分页读取时自动释放
+)
+
+// ModelStreamInfo 分页读取时首先返回的模型概要，只包含文件头和材质，打开文件时不会解析顶点、权重和形态键
+type ModelStreamInfo struct {
+	*COM3D2.ModelMetadata
+	FileSize int64 `json:"FileSize"` // 文件大小（字节）
+}
+
+// ModelStreamStructure 模型的骨骼和各部分数据的数量，用于决定如何分页读取
+type ModelStreamStructure struct {
+	Bones              []*COM3D2.Bone     `json:"Bones"`
+	BoneNames          []string           `json:"BoneNames"`
+	BindPoses          []COM3D2.Matrix4x4 `json:"BindPoses"`
+	VertexCount        int                `json:"VertexCount"`        // 顶点数
+	TangentCount       int                `json:"TangentCount"`       // 切线数，0 表示没有切线
+	BoneWeightCount    int                `json:"BoneWeightCount"`    // 骨骼权重数
+	SubMeshIndexCounts []int              `json:"SubMeshIndexCounts"` // 每个子网格的索引数
+	Morphs             []MorphInfo        `json:"Morphs"`             // 形态键概要
+	HasSkinThickness   bool               `json:"HasSkinThickness"`   // 是否有皮肤厚度数据
+}
+
+// ModelVertexPage 一页顶点数据，Tangents 与 Vertices 一一对应，模型没有切线时为空
+type ModelVertexPage struct {
+	Offset   int                 `json:"Offset"`
+	Total    int                 `json:"Total"`
+	Vertices []COM3D2.Vertex     `json:"Vertices"`
+	Tangents []COM3D2.Quaternion `json:"Tangents"`
+}
+
+// ModelBoneWeightPage 一页骨骼权重数据
+type ModelBoneWeightPage struct {
+	Offset      int                 `json:"Offset"`
+	Total       int                 `json:"Total"`
+	BoneWeights []COM3D2.BoneWeight `json:"BoneWeights"`
+}
+
+// ModelSubMeshIndexPage 一页子网格索引数据
+type ModelSubMeshIndexPage struct {
+	SubMeshIndex int     `json:"SubMeshIndex"`
+	Offset       int     `json:"Offset"`
+	Total        int     `json:"Total"`
+	Indices      []int32 `json:"Indices"`
+}
+
+// ModelMorphPage 一页形态键数据
+type ModelMorphPage struct {
+	Offset int                 `json:"Offset"`
+	Total  int                 `json:"Total"`
+	Morphs []*COM3D2.MorphData `json:"Morphs"`
+}
+
+// modelStreamEntry 缓存中的一个模型，ready 关闭后 model 和 err 才有效
+type modelStreamEntry struct {
+	model    *COM3D2.Model
+	err      error
+	ready    chan struct{}
+	modTime  time.Time
+	size     int64
+	lastUsed time.Time
+}
+
+// loaded 判断模型是否已读取完成
+func (e *modelStreamEntry) loaded() bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// modelStreamCache 分页读取使用的模型缓存，以文件路径为键，文件被修改后会自动重新读取
+// 总大小不超过 maxModelStreamCacheBytes，空闲超过 modelStreamIdleTimeout 的模型由定时器释放
+var modelStreamCache = struct {
+	sync.Mutex
+	entries map[string]*modelStreamEntry
+	bytes   int64
+	timer   *time.Timer
+}{entries: make(map[string]*modelStreamEntry)}
+
+// OpenModelStream 只读取 .model 文件头和材质并返回，不解析顶点等大块数据
+// 之后可以通过 ReadModelStructure、ReadModelVertices 等方法按需分页读取，第一次分页读取时会解析整个模型一次并缓存，之后的分页不再读取文件
+// 缓存的模型空闲 2 分钟后自动释放，缓存总大小超过 512MB 时释放最久未使用的其他模型，也可以调用 CloseModelStream 立即释放
+// 单个超过 512MB 的模型同样会被缓存，此时其他模型会被释放
+func (m *ModelService) OpenModelStream(path string) (info ModelStreamInfo, err error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return info, fmt.Errorf("cannot open .model file: %w", err)
+	}
+	metadata, err := m.ReadModelMetadata(path)
+	if err != nil {
+		return info, err
+	}
+	return ModelStreamInfo{ModelMetadata: metadata, FileSize: stat.Size()}, nil
+}
+
+// CloseModelStream 释放缓存的模型
+func (m *ModelService) CloseModelStream(path string) {
+	modelStreamCache.Lock()
+	defer modelStreamCache.Unlock()
+	removeModelStreamEntry(path)
+}
+
+// ReadModelStructure 读取骨骼和各部分数据的数量
+func (m *ModelService) ReadModelStructure(path string) (structure ModelStreamStructure, err error) {
+	modelData, err := m.streamModel(path)
+	if err != nil {
+		return structure, err
+	}
+	structure = ModelStreamStructure{
+		Bones:            modelData.Bones,
+		BoneNames:        modelData.BoneNames,
+		BindPoses:        modelData.BindPoses,
+		VertexCount:      len(modelData.Vertices),
+		TangentCount:     len(modelData.Tangents),
+		BoneWeightCount:  len(modelData.BoneWeights),
+		Morphs:           listMorphs(modelData),
+		HasSkinThickness: modelData.SkinThickness != nil,
+	}
+	structure.SubMeshIndexCounts = make([]int, len(modelData.SubMeshes))
+	for i, subMesh := range modelData.SubMeshes {
+		structure.SubMeshIndexCounts[i] = len(subMesh)
+	}
+	return structure, nil
+}
+
+// ReadModelVertices 分页读取顶点和切线，limit 小于等于 0 时读取到末尾
+func (m *ModelService) ReadModelVertices(path string, offset int, limit int) (page ModelVertexPage, err error) {
+	modelData, err := m.streamModel(path)
+	if err != nil {
+		return page, err
+	}
+	start, end, err := pageRange(len(modelData.Vertices), offset, limit)
+	if err != nil {
+		return page, err
+	}
+	page = ModelVertexPage{Offset: start, Total: len(modelData.Vertices), Vertices: modelData.Vertices[start:end]}
+	if len(modelData.Tangents) == len(modelData.Vertices) {
+		page.Tangents = modelData.Tangents[start:end]
+	}
+	return page, nil
+}
+
+// ReadModelBoneWeights 分页读取骨骼权重，limit 小于等于 0 时读取到末尾
+func (m *ModelService) ReadModelBoneWeights(path string, offset int, limit int) (page ModelBoneWeightPage, err error) {
+	modelData, err := m.streamModel(path)
+	if err != nil {
+		return page, err
+	}
+	start, end, err := pageRange(len(modelData.BoneWeights), offset, limit)
+	if err != nil {
+		return page, err
+	}
+	return ModelBoneWeightPage{Offset: start, Total: len(modelData.BoneWeights), BoneWeights: modelData.BoneWeights[start:end]}, nil
+}
+
+// ReadModelSubMeshIndices 分页读取子网格的三角形索引，limit 小于等于 0 时读取到末尾
+func (m *ModelService) ReadModelSubMeshIndices(path string, subMeshIndex int, offset int, limit int) (page ModelSubMeshIndexPage, err error) {
+	modelData, err := m.streamModel(path)
+	if err != nil {
+		return page, err
+	}
+	if subMeshIndex < 0 || subMeshIndex >= len(modelData.SubMeshes) {
+		return page, fmt.Errorf("submesh %d out of range [0, %d)", subMeshIndex, len(modelData.SubMeshes))
+	}
+	subMesh := modelData.SubMeshes[subMeshIndex]
+	start, end, err := pageRange(len(subMesh), offset, limit)
+	if err != nil {
+		return page, err
+	}
+	return ModelSubMeshIndexPage{SubMeshIndex: subMeshIndex, Offset: start, Total: len(subMesh), Indices: subMesh[start:end]}, nil
+}
+
+// ReadModelMorphs 分页读取形态键，以形态键为单位，limit 小于等于 0 时读取到末尾
+func (m *ModelService) ReadModelMorphs(path string, offset int, limit int) (page ModelMorphPage, err error) {
+	modelData, err := m.streamModel(path)
+	if err != nil {
+		return page, err
+	}
+	start, end, err := pageRange(len(modelData.MorphData), offset, limit)
+	if err != nil {
+		return page, err
+	}
+	return ModelMorphPage{Offset: start, Total: len(modelData.MorphData), Morphs: modelData.MorphData[start:end]}, nil
+}
+
+// streamModel 从缓存中取出模型，没有缓存或文件已被修改时重新读取
+// 同一文件同时有多个请求时只读取一次，其他请求等待读取完成
+func (m *ModelService) streamModel(path string) (*COM3D2.Model, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open .model file: %w", err)
+	}
+
+	modelStreamCache.Lock()
+	entry, ok := modelStreamCache.entries[path]
+	if ok && entry.modTime.Equal(stat.ModTime()) && entry.size == stat.Size() {
+		entry.lastUsed = time.Now()
+		modelStreamCache.Unlock()
+		<-entry.ready
+		return entry.model, entry.err
+	}
+	removeModelStreamEntry(path)
+	entry = &modelStreamEntry{
+		ready:    make(chan struct{}),
+		modTime:  stat.ModTime(),
+		size:     stat.Size(),
+		lastUsed: time.Now(),
+	}
+	modelStreamCache.entries[path] = entry
+	modelStreamCache.bytes += entry.size
+	modelStreamCache.Unlock()
+
+	// 读取文件时不持有锁，避免阻塞其他模型的分页请求
+	entry.model, entry.err = m.ReadModelFile(path)
+	close(entry.ready)
+
+	modelStreamCache.Lock()
+	defer modelStreamCache.Unlock()
+	if entry.err != nil {
+		if modelStreamCache.entries[path] == entry {
+			removeModelStreamEntry(path)
+		}
+		return nil, entry.err
+	}
+	for modelStreamCache.bytes > maxModelStreamCacheBytes {
+		var oldestPath string
+		var oldest time.Time
+		for p, e := range modelStreamCache.entries {
+			if e != entry && e.loaded() && (oldestPath == "" || e.lastUsed.Before(oldest)) {
+				oldestPath, oldest = p, e.lastUsed
+			}
+		}
+		if oldestPath == "" {
+			break
+		}
+		removeModelStreamEntry(oldestPath)
+	}
+	scheduleModelStreamSweep()
+	return entry.model, nil
+}
+
+// removeModelStreamEntry 从缓存中移除模型，调用时需持有锁
+func removeModelStreamEntry(path string) {
+	if entry, ok := modelStreamCache.entries[path]; ok {
+		modelStreamCache.bytes -= entry.size
+		delete(modelStreamCache.entries, path)
+	}
+}
+
+// scheduleModelStreamSweep 缓存不为空时启动定时器，定期释放空闲的模型，调用时需持有锁
+func scheduleModelStreamSweep() {
+	if modelStreamCache.timer != nil || len(modelStreamCache.entries) == 0 {
+		return
+	}
+	modelStreamCache.timer = time.AfterFunc(modelStreamIdleTimeout/2, func() {
+		modelStreamCache.Lock()
+		defer modelStreamCache.Unlock()
+		now := time.Now()
+		for p, e := range modelStreamCache.entries {
+			if e.loaded() && now.Sub(e.lastUsed) > modelStreamIdleTimeout {
+				removeModelStreamEntry(p)
+			}
+		}
+		modelStreamCache.timer = nil
+		scheduleModelStreamSweep()
+	})
+}
+
+// pageRange 计算分页的起止下标
+func pageRange(total int, offset int, limit int) (int, int, error) {
+	if offset < 0 || offset > total {
+		return 0, 0, fmt.Errorf("offset %d out of range [0, %d]", offset, total)
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return offset, end, nil
+}
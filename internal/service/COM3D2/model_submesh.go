@@ -0,0 +1,332 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 子网格拆分时的选择方式
+const (
+	SubMeshSelectVertices  = "vertices"  // 三个顶点都在 Vertices 中的三角形
+	SubMeshSelectTriangles = "triangles" // Triangles 中列出的三角形（子网格内的三角形序号）
+	SubMeshSelectUVIsland  = "uvIsland"  // 与 Triangles 中任一三角形处于同一 UV 岛的所有三角形
+	SubMeshSelectBones     = "bones"     // 三个顶点都受 Bones 中任一骨骼影响（权重不小于 MinWeight）的三角形
+)
+
+// SubMeshSplitOptions 拆分子网格的选项
+type SubMeshSplitOptions struct {
+	Mode      string   `json:"Mode"`      // 选择方式，见上方常量定义
+	Vertices  []int    `json:"Vertices"`  // 顶点索引，用于 vertices 模式
+	Triangles []int    `json:"Triangles"` // 子网格内的三角形序号，用于 triangles 和 uvIsland 模式
+	Bones     []string `json:"Bones"`     // 骨骼名，用于 bones 模式
+	MinWeight float32  `json:"MinWeight"` // 骨骼权重阈值，用于 bones 模式
+}
+
+// SubMeshSplitResult 拆分子网格的结果
+type SubMeshSplitResult struct {
+	NewSubMeshIndex    int `json:"NewSubMeshIndex"`    // 新子网格的索引
+	MovedTriangleCount int `json:"MovedTriangleCount"` // 移动到新子网格的三角形数
+	KeptTriangleCount  int `json:"KeptTriangleCount"`  // 留在原子网格的三角形数
+}
+
+// MergeSubMeshes 将 indices 中的子网格合并到第一个子网格中，其余子网格及其材质会被删除
+func (m *ModelService) MergeSubMeshes(inputPath string, outputPath string, indices []int) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		return mergeSubMeshes(modelData, indices)
+	})
+}
+
+// SplitSubMesh 将子网格中被选中的三角形拆分为新的子网格，新子网格追加在末尾，并复制原子网格的材质作为其材质
+func (m *ModelService) SplitSubMesh(inputPath string, outputPath string, subMeshIndex int, options SubMeshSplitOptions) (result SubMeshSplitResult, err error) {
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = splitSubMesh(modelData, subMeshIndex, options)
+		return err
+	})
+	return result, err
+}
+
+// ReorderMaterialSlots 按 order 重新排列材质槽，order[i] 为新位置 i 上的原槽位索引
+// 子网格与材质按下标一一对应，因此会同时移动子网格和材质
+func (m *ModelService) ReorderMaterialSlots(inputPath string, outputPath string, order []int) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		return reorderMaterialSlots(modelData, order)
+	})
+}
+
+// DuplicateMaterialSlot 复制材质槽（子网格及其材质），副本插入在 index+1，之后的槽位依次后移
+// 副本与原槽位的三角形相同，可以单独修改材质后再用 SplitSubMesh、MergeSubMeshes 等调整三角形
+func (m *ModelService) DuplicateMaterialSlot(inputPath string, outputPath string, index int) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		return duplicateMaterialSlot(modelData, index)
+	})
+}
+
+// mergeSubMeshes 就地合并子网格
+func mergeSubMeshes(modelData *COM3D2.Model, indices []int) error {
+	if len(indices) < 2 {
+		return fmt.Errorf("at least two submeshes are required to merge")
+	}
+	seen := make(map[int]struct{}, len(indices))
+	for _, index := range indices {
+		if index < 0 || index >= len(modelData.SubMeshes) {
+			return fmt.Errorf("submesh %d out of range [0, %d)", index, len(modelData.SubMeshes))
+		}
+		if _, dup := seen[index]; dup {
+			return fmt.Errorf("submesh %d is listed more than once", index)
+		}
+		seen[index] = struct{}{}
+	}
+
+	// 材质可能多于子网格（多 Pass），多出的部分保留
+	keep := make([]bool, max(len(modelData.SubMeshes), len(modelData.Materials)))
+	for i := range keep {
+		keep[i] = true
+	}
+	target := indices[0]
+	merged := slices.Clone(modelData.SubMeshes[target])
+	for _, index := range indices[1:] {
+		merged = append(merged, modelData.SubMeshes[index]...)
+		keep[index] = false
+	}
+	modelData.SubMeshes[target] = merged
+	modelData.SubMeshes = filterByMask(modelData.SubMeshes, keep)
+	modelData.SubMeshCount = int32(len(modelData.SubMeshes))
+	modelData.Materials = filterByMask(modelData.Materials, keep)
+	return nil
+}
+
+// duplicateMaterialSlot 就地复制材质槽
+func duplicateMaterialSlot(modelData *COM3D2.Model, index int) error {
+	if index < 0 || index >= len(modelData.SubMeshes) {
+		return fmt.Errorf("material slot %d out of range [0, %d)", index, len(modelData.SubMeshes))
+	}
+	if index >= len(modelData.Materials) {
+		return fmt.Errorf("material slot %d has no material", index)
+	}
+	material, err := cloneMaterial(modelData.Materials[index])
+	if err != nil {
+		return err
+	}
+	modelData.SubMeshes = slices.Insert(modelData.SubMeshes, index+1, slices.Clone(modelData.SubMeshes[index]))
+	modelData.SubMeshCount = int32(len(modelData.SubMeshes))
+	modelData.Materials = slices.Insert(modelData.Materials, index+1, material)
+	return nil
+}
+
+// splitSubMesh 就地拆分子网格
+func splitSubMesh(modelData *COM3D2.Model, subMeshIndex int, options SubMeshSplitOptions) (result SubMeshSplitResult, err error) {
+	if subMeshIndex < 0 || subMeshIndex >= len(modelData.SubMeshes) {
+		return result, fmt.Errorf("submesh %d out of range [0, %d)", subMeshIndex, len(modelData.SubMeshes))
+	}
+	subMesh := modelData.SubMeshes[subMeshIndex]
+	triangleCount := len(subMesh) / 3
+
+	selected, err := selectSubMeshTriangles(modelData, subMesh, options)
+	if err != nil {
+		return result, err
+	}
+
+	kept := make([]int32, 0, len(subMesh))
+	moved := make([]int32, 0, len(subMesh))
+	for t := 0; t < triangleCount; t++ {
+		if selected[t] {
+			moved = append(moved, subMesh[t*3:t*3+3]...)
+		} else {
+			kept = append(kept, subMesh[t*3:t*3+3]...)
+		}
+	}
+	if len(moved) == 0 {
+		return result, fmt.Errorf("the selection does not contain any triangle of submesh %d", subMeshIndex)
+	}
+	if len(kept) == 0 {
+		return result, fmt.Errorf("the selection contains every triangle of submesh %d, nothing to split", subMeshIndex)
+	}
+
+	// 新子网格使用原材质的副本
+	if len(modelData.Materials) < len(modelData.SubMeshes) {
+		return result, fmt.Errorf("model has %d submeshes but only %d materials", len(modelData.SubMeshes), len(modelData.Materials))
+	}
+	material, err := cloneMaterial(modelData.Materials[subMeshIndex])
+	if err != nil {
+		return result, err
+	}
+	newIndex := len(modelData.SubMeshes)
+	modelData.SubMeshes[subMeshIndex] = kept
+	modelData.SubMeshes = append(modelData.SubMeshes, moved)
+	modelData.SubMeshCount = int32(len(modelData.SubMeshes))
+	// 多出来的材质（多 Pass）需要保持在最后，因此新材质插入到原子网格数量的位置
+	modelData.Materials = append(modelData.Materials[:newIndex], append([]*COM3D2.Material{material}, modelData.Materials[newIndex:]...)...)
+
+	result.NewSubMeshIndex = newIndex
+	result.MovedTriangleCount = len(moved) / 3
+	result.KeptTriangleCount = len(kept) / 3
+	return result, nil
+}
+
+// selectSubMeshTriangles 根据选项返回子网格中每个三角形是否被选中
+func selectSubMeshTriangles(modelData *COM3D2.Model, subMesh []int32, options SubMeshSplitOptions) ([]bool, error) {
+	triangleCount := len(subMesh) / 3
+	selected := make([]bool, triangleCount)
+
+	switch options.Mode {
+	case SubMeshSelectVertices:
+		vertexSet := make(map[int32]struct{}, len(options.Vertices))
+		for _, v := range options.Vertices {
+			vertexSet[int32(v)] = struct{}{}
+		}
+		for t := 0; t < triangleCount; t++ {
+			selected[t] = true
+			for _, v := range subMesh[t*3 : t*3+3] {
+				if _, ok := vertexSet[v]; !ok {
+					selected[t] = false
+					break
+				}
+			}
+		}
+
+	case SubMeshSelectTriangles:
+		for _, t := range options.Triangles {
+			if t < 0 || t >= triangleCount {
+				return nil, fmt.Errorf("triangle %d out of range [0, %d)", t, triangleCount)
+			}
+			selected[t] = true
+		}
+
+	case SubMeshSelectUVIsland:
+		islands := uvIslands(modelData, subMesh)
+		wanted := make(map[int]struct{})
+		for _, t := range options.Triangles {
+			if t < 0 || t >= triangleCount {
+				return nil, fmt.Errorf("triangle %d out of range [0, %d)", t, triangleCount)
+			}
+			wanted[islands[t]] = struct{}{}
+		}
+		for t := 0; t < triangleCount; t++ {
+			_, selected[t] = wanted[islands[t]]
+		}
+
+	case SubMeshSelectBones:
+		boneSet := make(map[int]struct{}, len(options.Bones))
+		for _, name := range options.Bones {
+			found := false
+			for i, boneName := range modelData.BoneNames {
+				if boneName == name {
+					boneSet[i] = struct{}{}
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("bone %q is not a skinning bone of this model", name)
+			}
+		}
+		influenced := func(v int32) bool {
+			if int(v) >= len(modelData.BoneWeights) {
+				return false
+			}
+			for _, inf := range getBoneInfluences(modelData.BoneWeights[v]) {
+				if _, ok := boneSet[inf.Index]; ok && inf.Weight > 0 && inf.Weight >= options.MinWeight {
+					return true
+				}
+			}
+			return false
+		}
+		for t := 0; t < triangleCount; t++ {
+			selected[t] = influenced(subMesh[t*3]) && influenced(subMesh[t*3+1]) && influenced(subMesh[t*3+2])
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown submesh selection mode: %q", options.Mode)
+	}
+
+	return selected, nil
+}
+
+// uvIslands 计算子网格中每个三角形所属的 UV 岛编号
+// 共享同一顶点，或者共享位置与 UV 都相同的顶点的三角形属于同一个岛
+func uvIslands(modelData *COM3D2.Model, subMesh []int32) []int {
+	triangleCount := len(subMesh) / 3
+	parent := make([]int, triangleCount)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		for parent[x] != x {
+			parent[x] = parent[parent[x]]
+			x = parent[x]
+		}
+		return x
+	}
+
+	type uvKey struct {
+		Position COM3D2.Vector3
+		UV       COM3D2.Vector2
+	}
+	owner := make(map[uvKey]int)
+	for t := 0; t < triangleCount; t++ {
+		for _, v := range subMesh[t*3 : t*3+3] {
+			if int(v) >= len(modelData.Vertices) {
+				continue
+			}
+			key := uvKey{Position: modelData.Vertices[v].Position, UV: modelData.Vertices[v].UV}
+			if other, ok := owner[key]; ok {
+				parent[find(t)] = find(other)
+			} else {
+				owner[key] = t
+			}
+		}
+	}
+
+	islands := make([]int, triangleCount)
+	for t := range islands {
+		islands[t] = find(t)
+	}
+	return islands
+}
+
+// reorderMaterialSlots 就地重新排列子网格和材质
+func reorderMaterialSlots(modelData *COM3D2.Model, order []int) error {
+	slotCount := len(modelData.SubMeshes)
+	if len(order) != slotCount {
+		return fmt.Errorf("order must list all %d material slots, got %d", slotCount, len(order))
+	}
+	if len(modelData.Materials) < slotCount {
+		return fmt.Errorf("model has %d submeshes but only %d materials", slotCount, len(modelData.Materials))
+	}
+	used := make([]bool, slotCount)
+	for _, index := range order {
+		if index < 0 || index >= slotCount || used[index] {
+			return fmt.Errorf("order is not a permutation of [0, %d)", slotCount)
+		}
+		used[index] = true
+	}
+
+	subMeshes := make([][]int32, slotCount)
+	materials := make([]*COM3D2.Material, 0, len(modelData.Materials))
+	for newIndex, oldIndex := range order {
+		subMeshes[newIndex] = modelData.SubMeshes[oldIndex]
+		materials = append(materials, modelData.Materials[oldIndex])
+	}
+	// 超出子网格数量的材质保持原位
+	materials = append(materials, modelData.Materials[slotCount:]...)
+
+	modelData.SubMeshes = subMeshes
+	modelData.Materials = materials
+	return nil
+}
+
+// cloneMaterial 通过 JSON 深拷贝材质
+func cloneMaterial(material *COM3D2.Material) (*COM3D2.Material, error) {
+	data, err := json.Marshal(material)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal material: %w", err)
+	}
+	duplicate := &COM3D2.Material{}
+	if err := json.Unmarshal(data, duplicate); err != nil {
+		return nil, fmt.Errorf("failed to copy material: %w", err)
+	}
+	return duplicate, nil
+}
@@ -0,0 +1,341 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// SkinThickness 数据块的固定签名和版本
+const (
+	skinThicknessSignature = "SkinThickness"
+	skinThicknessVersion   = 100
+)
+
+// SkinThicknessGenerateOptions 自动生成皮肤厚度组的选项
+type SkinThicknessGenerateOptions struct {
+	GroupName       string `json:"GroupName"`       // 组名，已存在时会被覆盖
+	StartBoneName   string `json:"StartBoneName"`   // 骨骼链起点
+	EndBoneName     string `json:"EndBoneName"`     // 骨骼链终点
+	TargetBoneName  string `json:"TargetBoneName"`  // 各采样点的目标骨骼，为空时使用 StartBoneName
+	StepAngleDegree int32  `json:"StepAngleDegree"` // 绕骨骼轴采样的角度间隔（度）
+	PointCount      int    `json:"PointCount"`      // 沿骨骼链采样的点数，至少为 2，包含起点和终点
+}
+
+// SkinThicknessGenerateResult 自动生成皮肤厚度组的结果
+type SkinThicknessGenerateResult struct {
+	Group       *COM3D2.ThickGroup `json:"Group"`       // 生成的组
+	HitCount    int                `json:"HitCount"`    // 射线命中网格的次数
+	MissedCount int                `json:"MissedCount"` // 射线未命中网格的次数，这些角度的距离由相邻角度插值得到
+}
+
+// ReadSkinThickness 读取 .model 文件，只返回其中的皮肤厚度数据，没有时返回 nil
+func (m *ModelService) ReadSkinThickness(path string) (*COM3D2.SkinThickness, error) {
+	modelData, err := m.ReadModelFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model file: %w", err)
+	}
+	return modelData.SkinThickness, nil
+}
+
+// SetSkinThicknessUse 设置是否启用皮肤厚度，没有皮肤厚度数据时会创建一个空的数据块
+func (m *ModelService) SetSkinThicknessUse(inputPath string, outputPath string, use bool) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		skinThickness, err := ensureSkinThickness(modelData)
+		if err != nil {
+			return err
+		}
+		skinThickness.Use = use
+		return nil
+	})
+}
+
+// SetSkinThicknessGroup 新增或替换皮肤厚度组，以 group.GroupName 作为键
+func (m *ModelService) SetSkinThicknessGroup(inputPath string, outputPath string, group *COM3D2.ThickGroup) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		if group == nil || group.GroupName == "" {
+			return fmt.Errorf("skin thickness group name cannot be empty")
+		}
+		skinThickness, err := ensureSkinThickness(modelData)
+		if err != nil {
+			return err
+		}
+		skinThickness.Groups[group.GroupName] = group
+		return nil
+	})
+}
+
+// DeleteSkinThicknessGroup 删除皮肤厚度组
+func (m *ModelService) DeleteSkinThicknessGroup(inputPath string, outputPath string, groupName string) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		if _, err := findThickGroup(modelData, groupName); err != nil {
+			return err
+		}
+		delete(modelData.SkinThickness.Groups, groupName)
+		return nil
+	})
+}
+
+// SetSkinThicknessPoint 替换皮肤厚度组中的采样点，pointIndex 等于点数时追加到末尾
+func (m *ModelService) SetSkinThicknessPoint(inputPath string, outputPath string, groupName string, pointIndex int, point *COM3D2.ThickPoint) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		group, err := findThickGroup(modelData, groupName)
+		if err != nil {
+			return err
+		}
+		if point == nil {
+			return fmt.Errorf("skin thickness point cannot be empty")
+		}
+		switch {
+		case pointIndex == len(group.Points):
+			group.Points = append(group.Points, point)
+		case pointIndex >= 0 && pointIndex < len(group.Points):
+			group.Points[pointIndex] = point
+		default:
+			return fmt.Errorf("point %d out of range [0, %d]", pointIndex, len(group.Points))
+		}
+		return nil
+	})
+}
+
+// DeleteSkinThicknessPoint 删除皮肤厚度组中的采样点
+func (m *ModelService) DeleteSkinThicknessPoint(inputPath string, outputPath string, groupName string, pointIndex int) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		group, err := findThickGroup(modelData, groupName)
+		if err != nil {
+			return err
+		}
+		if pointIndex < 0 || pointIndex >= len(group.Points) {
+			return fmt.Errorf("point %d out of range [0, %d)", pointIndex, len(group.Points))
+		}
+		group.Points = append(group.Points[:pointIndex], group.Points[pointIndex+1:]...)
+		return nil
+	})
+}
+
+// GenerateSkinThicknessGroup 沿骨骼链自动生成皮肤厚度组，并写入 outputPath
+// 在起点到终点之间均匀取 PointCount 个点，从每个点向骨骼轴的垂直方向每隔 StepAngleDegree 度发射射线，
+// 记录命中的网格位置到骨骼轴的距离以及最近的顶点
+// 0 度方向为起点骨骼局部 +Y 轴（与骨骼链几乎平行时为 +Z 轴）在垂直平面上的投影，角度按右手定则绕骨骼链方向增加
+func (m *ModelService) GenerateSkinThicknessGroup(inputPath string, outputPath string, options SkinThicknessGenerateOptions) (result SkinThicknessGenerateResult, err error) {
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = generateSkinThicknessGroup(modelData, options)
+		if err != nil {
+			return err
+		}
+		skinThickness, err := ensureSkinThickness(modelData)
+		if err != nil {
+			return err
+		}
+		skinThickness.Groups[result.Group.GroupName] = result.Group
+		return nil
+	})
+	return result, err
+}
+
+// generateSkinThicknessGroup 通过射线检测生成皮肤厚度组
+func generateSkinThicknessGroup(modelData *COM3D2.Model, options SkinThicknessGenerateOptions) (result SkinThicknessGenerateResult, err error) {
+	if options.GroupName == "" {
+		return result, fmt.Errorf("skin thickness group name cannot be empty")
+	}
+	if options.StepAngleDegree <= 0 || options.StepAngleDegree > 180 {
+		return result, fmt.Errorf("step angle must be in (0, 180], got %d", options.StepAngleDegree)
+	}
+	if options.PointCount < 2 {
+		return result, fmt.Errorf("point count must be at least 2, got %d", options.PointCount)
+	}
+	targetBone := options.TargetBoneName
+	if targetBone == "" {
+		targetBone = options.StartBoneName
+	}
+
+	startIndex := findBone(modelData, options.StartBoneName)
+	if startIndex < 0 {
+		return result, fmt.Errorf("start bone %q not found", options.StartBoneName)
+	}
+	endIndex := findBone(modelData, options.EndBoneName)
+	if endIndex < 0 {
+		return result, fmt.Errorf("end bone %q not found", options.EndBoneName)
+	}
+	if findBone(modelData, targetBone) < 0 {
+		return result, fmt.Errorf("target bone %q not found", targetBone)
+	}
+
+	boneWorld := boneWorldMatrices(modelData.Bones)
+	start := bonePositionInMeshSpace(modelData, boneWorld, startIndex)
+	end := bonePositionInMeshSpace(modelData, boneWorld, endIndex)
+	axis := vec3Sub(end, start)
+	if vec3Length(axis) == 0 {
+		return result, fmt.Errorf("start bone and end bone are at the same position")
+	}
+	axisDir := vec3Normalize(axis)
+	reference := thicknessReferenceDirection(modelData, boneWorld, startIndex, axisDir)
+
+	triangles, err := collectTriangles(modelData, -1)
+	if err != nil {
+		return result, err
+	}
+	positions := vertexPositions(modelData)
+
+	// 游戏按 AngleDegree / StepAngleDegree 的下标读取 DistanceParAngle，因此每个角度都要写入
+	type rayHit struct {
+		distance float32
+		vertex   int
+		hit      bool
+	}
+	stepCount := int((359 + options.StepAngleDegree) / options.StepAngleDegree)
+	origins := make([]COM3D2.Vector3, options.PointCount)
+	directions := make([]COM3D2.Vector3, stepCount)
+	for k := range directions {
+		directions[k] = quatRotate(quatFromAxisAngle(axisDir, float64(int32(k)*options.StepAngleDegree)*math.Pi/180), reference)
+	}
+	hits := make([][]rayHit, options.PointCount)
+	var hitDistanceSum float32
+	for p := range hits {
+		origins[p] = vec3Add(start, vec3Scale(axis, float32(p)/float32(options.PointCount-1)))
+		hits[p] = make([]rayHit, stepCount)
+		for k, dir := range directions {
+			distance, vertexIndex, hit := raycastMesh(positions, triangles, origins[p], dir)
+			if !hit {
+				result.MissedCount++
+				continue
+			}
+			result.HitCount++
+			hitDistanceSum += distance
+			hits[p][k] = rayHit{distance: distance, vertex: vertexIndex, hit: true}
+		}
+	}
+	if result.HitCount == 0 {
+		return result, fmt.Errorf("no ray hit the mesh, check that the bone chain is inside the mesh")
+	}
+	meanDistance := hitDistanceSum / float32(result.HitCount)
+
+	group := &COM3D2.ThickGroup{
+		GroupName:       options.GroupName,
+		StartBoneName:   options.StartBoneName,
+		EndBoneName:     options.EndBoneName,
+		StepAngleDegree: options.StepAngleDegree,
+	}
+	for p := range hits {
+		ratio := float32(p) / float32(options.PointCount-1)
+		point := &COM3D2.ThickPoint{
+			TargetBoneName:         targetBone,
+			RatioSegmentStartToEnd: ratio,
+		}
+		for k := range directions {
+			h := hits[p][k]
+			if !h.hit {
+				// 未命中时按环绕方向上前后最近的命中角度线性插值距离，整个点都未命中时使用所有命中距离的平均值
+				h.distance = meanDistance
+				for before := 1; before < stepCount; before++ {
+					prev := hits[p][(k-before+stepCount)%stepCount]
+					if !prev.hit {
+						continue
+					}
+					for after := 1; after < stepCount; after++ {
+						next := hits[p][(k+after)%stepCount]
+						if next.hit {
+							t := float32(before) / float32(before+after)
+							h.distance = prev.distance + (next.distance-prev.distance)*t
+							break
+						}
+					}
+					break
+				}
+				h.vertex = nearestVertex(positions, vec3Add(origins[p], vec3Scale(directions[k], h.distance)))
+			}
+			point.DistanceParAngle = append(point.DistanceParAngle, &COM3D2.ThickDefPerAngle{
+				AngleDegree:     int32(k) * options.StepAngleDegree,
+				VertexIndex:     int32(h.vertex),
+				DefaultDistance: h.distance,
+			})
+		}
+		group.Points = append(group.Points, point)
+	}
+
+	result.Group = group
+	return result, nil
+}
+
+// thicknessReferenceDirection 返回 AngleDegree 为 0 的方向：起点骨骼局部 +Y 轴投影到与骨骼链垂直的平面上
+// Biped 骨骼沿局部 X 轴延伸，角度按右手定则绕骨骼链方向增加；+Y 与骨骼链几乎平行时改用局部 +Z 轴
+func thicknessReferenceDirection(modelData *COM3D2.Model, boneWorld []mat4, startIndex int, axisDir COM3D2.Vector3) COM3D2.Vector3 {
+	for _, localAxis := range []COM3D2.Vector3{{Y: 1}, {Z: 1}} {
+		dir := boneAxisInMeshSpace(modelData, boneWorld, startIndex, localAxis)
+		projected := vec3Sub(dir, vec3Scale(axisDir, vec3Dot(dir, axisDir)))
+		if vec3Length(projected) > 0.1 {
+			return vec3Normalize(projected)
+		}
+	}
+	return anyPerpendicular(axisDir)
+}
+
+// nearestVertex 返回离 p 最近的顶点
+func nearestVertex(positions []COM3D2.Vector3, p COM3D2.Vector3) int {
+	nearest := -1
+	nearestDist := float32(math.MaxFloat32)
+	for i, position := range positions {
+		if d := vec3Length(vec3Sub(position, p)); d < nearestDist {
+			nearest, nearestDist = i, d
+		}
+	}
+	return nearest
+}
+
+// raycastMesh 返回射线与网格最近交点的距离，以及命中三角形中离交点最近的顶点
+func raycastMesh(positions []COM3D2.Vector3, triangles [][3]int, origin, dir COM3D2.Vector3) (float32, int, bool) {
+	bestT := float32(math.MaxFloat32)
+	bestTriangle := -1
+	for i, tri := range triangles {
+		t, ok := rayTriangle(origin, dir, positions[tri[0]], positions[tri[1]], positions[tri[2]])
+		if ok && t < bestT {
+			bestT = t
+			bestTriangle = i
+		}
+	}
+	if bestTriangle < 0 {
+		return 0, -1, false
+	}
+
+	hit := vec3Add(origin, vec3Scale(dir, bestT))
+	nearest := -1
+	nearestDist := float32(math.MaxFloat32)
+	for _, v := range triangles[bestTriangle] {
+		if d := vec3Length(vec3Sub(positions[v], hit)); d < nearestDist {
+			nearest = v
+			nearestDist = d
+		}
+	}
+	return bestT, nearest, true
+}
+
+// ensureSkinThickness 返回模型的皮肤厚度数据，没有时创建一个空的数据块
+func ensureSkinThickness(modelData *COM3D2.Model) (*COM3D2.SkinThickness, error) {
+	if modelData.Version < 2000 {
+		return nil, fmt.Errorf("model version %d does not support SkinThickness, convert it to version 2000 or later first", modelData.Version)
+	}
+	if modelData.SkinThickness == nil {
+		modelData.SkinThickness = &COM3D2.SkinThickness{
+			Signature: skinThicknessSignature,
+			Version:   skinThicknessVersion,
+			Use:       true,
+		}
+	}
+	if modelData.SkinThickness.Groups == nil {
+		modelData.SkinThickness.Groups = make(map[string]*COM3D2.ThickGroup)
+	}
+	return modelData.SkinThickness, nil
+}
+
+// findThickGroup 按名称查找皮肤厚度组
+func findThickGroup(modelData *COM3D2.Model, groupName string) (*COM3D2.ThickGroup, error) {
+	if modelData.SkinThickness == nil {
+		return nil, fmt.Errorf("model has no skin thickness data")
+	}
+	group, ok := modelData.SkinThickness.Groups[groupName]
+	if !ok || group == nil {
+		return nil, fmt.Errorf("skin thickness group %q not found", groupName)
+	}
+	return group, nil
+}
@@ -0,0 +1,248 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// ModelTransformOptions 模型整体变换选项，变换顺序为缩放 -> 旋转 -> 平移
+type ModelTransformOptions struct {
+	Translation    COM3D2.Vector3 `json:"Translation"`    // 平移
+	Rotation       COM3D2.Vector3 `json:"Rotation"`       // 欧拉角（度），与 Unity 一致按 Z、X、Y 顺序旋转
+	Scale          COM3D2.Vector3 `json:"Scale"`          // 缩放，分量为 0 时视为 1
+	PivotBone      string         `json:"PivotBone"`      // 以该骨骼的位置为中心进行旋转和缩放，为空时以原点为中心
+	TransformBones bool           `json:"TransformBones"` // 是否同时变换骨骼，设置 PivotBone 时只变换该骨骼及其子骨骼
+}
+
+// TransformModel 对模型进行平移、旋转、缩放，写入 outputPath
+// 顶点位置、法线、切线和形态键偏移总是会被变换
+// TransformBones 为 false 时骨骼和 BindPoses 保持不变，网格相对于骨骼移动，适用于调整跟随身体骨骼的饰品
+// TransformBones 为 true 时骨骼关节随网格一起移动（骨骼本身不会被缩放），并修正对应的 BindPoses 使蒙皮保持正确，适用于带有自身骨骼（如摇晃骨骼）的模型
+func (m *ModelService) TransformModel(inputPath string, outputPath string, options ModelTransformOptions) error {
+	return m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		return transformModel(modelData, options)
+	})
+}
+
+// transformModel 就地变换模型
+func transformModel(modelData *COM3D2.Model, options ModelTransformOptions) error {
+	scale := options.Scale
+	if scale.X == 0 {
+		scale.X = 1
+	}
+	if scale.Y == 0 {
+		scale.Y = 1
+	}
+	if scale.Z == 0 {
+		scale.Z = 1
+	}
+	rotation := quatFromEuler(options.Rotation)
+
+	boneWorld := boneWorldMatrices(modelData.Bones)
+	pivotIndex := -1
+	var pivot COM3D2.Vector3
+	if options.PivotBone != "" {
+		pivotIndex = findBone(modelData, options.PivotBone)
+		if pivotIndex < 0 {
+			return fmt.Errorf("pivot bone %q not found", options.PivotBone)
+		}
+		pivot = bonePositionInMeshSpace(modelData, boneWorld, pivotIndex)
+	}
+
+	// T = 平移(pivot + translation) * R * S * 平移(-pivot)
+	transform := mat4Mul(
+		mat4TRS(vec3Add(pivot, options.Translation), rotation, scale),
+		mat4TRS(vec3Scale(pivot, -1), quatIdentity(), COM3D2.Vector3{X: 1, Y: 1, Z: 1}),
+	)
+	inverseTransform, ok := transform.inverse()
+	if !ok {
+		return fmt.Errorf("transform is not invertible")
+	}
+	normalMatrix := transform.normalMatrix()
+	mirrored := transform.determinant3() < 0
+
+	// 形态键需要在顶点变换之前处理，法线偏移按原法线计算变换后的差值
+	for _, morph := range modelData.MorphData {
+		for i, vertexIndex := range morph.Indices {
+			if i < len(morph.Vertex) {
+				morph.Vertex[i] = transform.mulVector(morph.Vertex[i])
+			}
+			v := int(vertexIndex)
+			if i < len(morph.Normals) && v < len(modelData.Vertices) {
+				base := modelData.Vertices[v].Normal
+				newBase := vec3Normalize(normalMatrix.mulVector(base))
+				morphed := vec3Normalize(normalMatrix.mulVector(vec3Add(base, morph.Normals[i])))
+				morph.Normals[i] = vec3Sub(morphed, newBase)
+			}
+			if i < len(morph.Tangents) {
+				t := &morph.Tangents[i]
+				d := transform.mulVector(COM3D2.Vector3{X: t.X, Y: t.Y, Z: t.Z})
+				t.X, t.Y, t.Z = d.X, d.Y, d.Z
+			}
+		}
+	}
+
+	// 顶点
+	for i := range modelData.Vertices {
+		vertex := &modelData.Vertices[i]
+		vertex.Position = transform.mulPoint(vertex.Position)
+		vertex.Normal = vec3Normalize(normalMatrix.mulVector(vertex.Normal))
+	}
+	for i := range modelData.Tangents {
+		t := &modelData.Tangents[i]
+		d := vec3Normalize(transform.mulVector(COM3D2.Vector3{X: t.X, Y: t.Y, Z: t.Z}))
+		t.X, t.Y, t.Z = d.X, d.Y, d.Z
+		if mirrored {
+			t.W = -t.W
+		}
+	}
+
+	// 镜像变换会翻转三角形朝向，需要调换顶点顺序
+	if mirrored {
+		for _, subMesh := range modelData.SubMeshes {
+			for t := 0; t+2 < len(subMesh); t += 3 {
+				subMesh[t+1], subMesh[t+2] = subMesh[t+2], subMesh[t+1]
+			}
+		}
+	}
+
+	// SkinThickness 中的距离按平均缩放比例调整
+	if modelData.SkinThickness != nil {
+		uniform := float32(math.Cbrt(math.Abs(float64(scale.X * scale.Y * scale.Z))))
+		for _, group := range modelData.SkinThickness.Groups {
+			if group == nil {
+				continue
+			}
+			for _, point := range group.Points {
+				if point == nil {
+					continue
+				}
+				for _, d := range point.DistanceParAngle {
+					if d != nil {
+						d.DefaultDistance *= uniform
+					}
+				}
+			}
+		}
+	}
+
+	if options.TransformBones {
+		transformBones(modelData, boneWorld, transform, rotation, pivotIndex, inverseTransform)
+	}
+
+	return nil
+}
+
+// transformBones 变换骨骼关节位置并修正 BindPoses
+// 新的世界矩阵为：线性部分 R * L，平移部分 T(p)，即关节位置完整变换，但骨骼只旋转不缩放
+func transformBones(modelData *COM3D2.Model, boneWorld []mat4, transform mat4, rotation COM3D2.Quaternion, pivotIndex int, inverseTransform mat4) {
+	bones := modelData.Bones
+	affected := make([]bool, len(bones))
+	for i := range bones {
+		affected[i] = pivotIndex < 0 || isBoneDescendant(bones, i, pivotIndex)
+	}
+
+	worldRotations := boneWorldRotations(bones)
+	rotationMatrix := mat4TRS(COM3D2.Vector3{}, rotation, COM3D2.Vector3{X: 1, Y: 1, Z: 1})
+	newWorld := make([]mat4, len(bones))
+	for i := range bones {
+		if !affected[i] {
+			newWorld[i] = boneWorld[i]
+			continue
+		}
+		w := mat4Mul(rotationMatrix, boneWorld[i])
+		p := transform.mulPoint(boneWorld[i].translation())
+		w[12], w[13], w[14] = float64(p.X), float64(p.Y), float64(p.Z)
+		newWorld[i] = w
+	}
+
+	for i := range bones {
+		if !affected[i] {
+			continue
+		}
+		parent := int(bones[i].ParentIndex)
+		if parent < 0 || parent >= len(bones) {
+			bones[i].Position = newWorld[i].translation()
+			bones[i].Rotation = quatNormalize(quatMul(rotation, bones[i].Rotation))
+			continue
+		}
+		parentInverse, ok := newWorld[parent].inverse()
+		if !ok {
+			continue
+		}
+		bones[i].Position = parentInverse.mulPoint(newWorld[i].translation())
+		if !affected[parent] {
+			// 父骨骼未变换，局部旋转需要吸收整体旋转
+			bones[i].Rotation = quatNormalize(quatMul(quatMul(quatConjugate(worldRotations[parent]), rotation), worldRotations[i]))
+		}
+	}
+
+	// P' = W'^-1 * T * W * P * T^-1，保证静止姿态下蒙皮结果等于变换后的原结果
+	recomputed := boneWorldMatrices(bones)
+	for skinIndex, name := range modelData.BoneNames {
+		if skinIndex >= len(modelData.BindPoses) {
+			break
+		}
+		boneIndex := findBone(modelData, name)
+		if boneIndex < 0 || !affected[boneIndex] {
+			continue
+		}
+		newInverse, ok := recomputed[boneIndex].inverse()
+		if !ok {
+			continue
+		}
+		bindPose := mat4FromMatrix4x4(modelData.BindPoses[skinIndex])
+		corrected := mat4Mul(mat4Mul(mat4Mul(mat4Mul(newInverse, transform), boneWorld[boneIndex]), bindPose), inverseTransform)
+		modelData.BindPoses[skinIndex] = corrected.toMatrix4x4()
+	}
+}
+
+// findBone 按名称查找骨骼在 Bones 中的索引，找不到时返回 -1
+func findBone(modelData *COM3D2.Model, name string) int {
+	for i, bone := range modelData.Bones {
+		if bone.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBoneDescendant 判断骨骼 i 是否为 ancestor 本身或其子孙
+func isBoneDescendant(bones []*COM3D2.Bone, i int, ancestor int) bool {
+	for steps := 0; i >= 0 && i < len(bones) && steps <= len(bones); steps++ {
+		if i == ancestor {
+			return true
+		}
+		i = int(bones[i].ParentIndex)
+	}
+	return false
+}
+
+// bonePositionInMeshSpace 返回骨骼在网格空间中的位置
+// 蒙皮骨骼优先使用 BindPose 的逆矩阵，这样即使骨骼静止姿态与绑定姿态不一致也能得到网格空间中的位置
+func bonePositionInMeshSpace(modelData *COM3D2.Model, boneWorld []mat4, boneIndex int) COM3D2.Vector3 {
+	name := modelData.Bones[boneIndex].Name
+	for skinIndex, skinName := range modelData.BoneNames {
+		if skinName == name && skinIndex < len(modelData.BindPoses) {
+			if inv, ok := mat4FromMatrix4x4(modelData.BindPoses[skinIndex]).inverse(); ok {
+				return inv.translation()
+			}
+		}
+	}
+	return boneWorld[boneIndex].translation()
+}
+
+// boneAxisInMeshSpace 返回骨骼静止姿态下的局部轴在网格空间中的方向，与 bonePositionInMeshSpace 一样优先使用 BindPose
+func boneAxisInMeshSpace(modelData *COM3D2.Model, boneWorld []mat4, boneIndex int, localAxis COM3D2.Vector3) COM3D2.Vector3 {
+	name := modelData.Bones[boneIndex].Name
+	for skinIndex, skinName := range modelData.BoneNames {
+		if skinName == name && skinIndex < len(modelData.BindPoses) {
+			if inv, ok := mat4FromMatrix4x4(modelData.BindPoses[skinIndex]).inverse(); ok {
+				return vec3Normalize(inv.mulVector(localAxis))
+			}
+		}
+	}
+	return vec3Normalize(boneWorld[boneIndex].mulVector(localAxis))
+}
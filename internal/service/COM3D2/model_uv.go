@@ -0,0 +1,135 @@
+package COM3D2
+
+import (
+	"fmt"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// UVEditResult UV 编辑结果
+type UVEditResult struct {
+	VertexCount       int `json:"VertexCount"`       // 被修改的顶点数
+	SharedVertexCount int `json:"SharedVertexCount"` // 同时被其他子网格使用的顶点数，这些顶点在其他子网格中的 UV 也会改变
+	OutOfRangeCount   int `json:"OutOfRangeCount"`   // 修改前 UV 超出 [0, 1] 的顶点数，映射到图集时这些顶点会落在矩形之外
+}
+
+// FlipUV 翻转子网格的 V 坐标（v = 1 - v），subMeshIndex 为 -1 时处理整个模型
+func (m *ModelService) FlipUV(inputPath string, outputPath string, subMeshIndex int) (result UVEditResult, err error) {
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = editSubMeshUV(modelData, subMeshIndex, func(uv COM3D2.Vector2) COM3D2.Vector2 {
+			return COM3D2.Vector2{X: uv.X, Y: 1 - uv.Y}
+		})
+		return err
+	})
+	return result, err
+}
+
+// TransformUV 对子网格的 UV 先缩放再平移（uv * scale + offset），scale 分量为 0 时视为 1，subMeshIndex 为 -1 时处理整个模型
+func (m *ModelService) TransformUV(inputPath string, outputPath string, subMeshIndex int, offset COM3D2.Vector2, scale COM3D2.Vector2) (result UVEditResult, err error) {
+	if scale.X == 0 {
+		scale.X = 1
+	}
+	if scale.Y == 0 {
+		scale.Y = 1
+	}
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = editSubMeshUV(modelData, subMeshIndex, func(uv COM3D2.Vector2) COM3D2.Vector2 {
+			return COM3D2.Vector2{X: uv.X*scale.X + offset.X, Y: uv.Y*scale.Y + offset.Y}
+		})
+		return err
+	})
+	return result, err
+}
+
+// RemapUVToAtlasRect 将子网格的 UV 映射到 .tex 图集中第 rectIndex 个矩形内，subMeshIndex 为 -1 时处理整个模型
+// 原本覆盖整张贴图的 [0, 1] 区间会被压缩到矩形所在的区域
+func (m *ModelService) RemapUVToAtlasRect(inputPath string, outputPath string, subMeshIndex int, texPath string, rectIndex int) (result UVEditResult, err error) {
+	texService := &TexService{}
+	texData, err := texService.ReadTexFile(texPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read tex file: %w", err)
+	}
+	if rectIndex < 0 || rectIndex >= len(texData.Rects) {
+		return result, fmt.Errorf("rect %d out of range [0, %d), tex file has %d rects", rectIndex, len(texData.Rects), len(texData.Rects))
+	}
+	rect := normalizeTexRect(texData.Rects[rectIndex], texData.Width, texData.Height)
+
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = editSubMeshUV(modelData, subMeshIndex, func(uv COM3D2.Vector2) COM3D2.Vector2 {
+			return COM3D2.Vector2{X: rect.X + uv.X*rect.W, Y: rect.Y + uv.Y*rect.H}
+		})
+		return err
+	})
+	return result, err
+}
+
+// normalizeTexRect 返回以 [0, 1] 表示的矩形
+// 游戏使用的矩形已经是归一化的坐标，但部分工具会写入像素坐标，分量超过 1 时按贴图尺寸换算
+func normalizeTexRect(rect COM3D2.TexRect, width int32, height int32) COM3D2.TexRect {
+	if (rect.X > 1 || rect.W > 1) && width > 0 {
+		rect.X /= float32(width)
+		rect.W /= float32(width)
+	}
+	if (rect.Y > 1 || rect.H > 1) && height > 0 {
+		rect.Y /= float32(height)
+		rect.H /= float32(height)
+	}
+	return rect
+}
+
+// editSubMeshUV 对子网格引用的每个顶点调用一次 edit，subMeshIndex 为 -1 时处理所有顶点
+func editSubMeshUV(modelData *COM3D2.Model, subMeshIndex int, edit func(uv COM3D2.Vector2) COM3D2.Vector2) (result UVEditResult, err error) {
+	selected, err := subMeshVertexMask(modelData, subMeshIndex)
+	if err != nil {
+		return result, err
+	}
+
+	if subMeshIndex >= 0 {
+		shared := make(map[int32]bool)
+		for i, subMesh := range modelData.SubMeshes {
+			if i == subMeshIndex {
+				continue
+			}
+			for _, v := range subMesh {
+				if v >= 0 && int(v) < len(selected) && selected[v] {
+					shared[v] = true
+				}
+			}
+		}
+		result.SharedVertexCount = len(shared)
+	}
+
+	for v, ok := range selected {
+		if !ok {
+			continue
+		}
+		uv := modelData.Vertices[v].UV
+		if uv.X < 0 || uv.X > 1 || uv.Y < 0 || uv.Y > 1 {
+			result.OutOfRangeCount++
+		}
+		modelData.Vertices[v].UV = edit(uv)
+		result.VertexCount++
+	}
+	return result, nil
+}
+
+// subMeshVertexMask 返回子网格引用的顶点标记，subMeshIndex 为 -1 时标记所有顶点
+func subMeshVertexMask(modelData *COM3D2.Model, subMeshIndex int) ([]bool, error) {
+	mask := make([]bool, len(modelData.Vertices))
+	if subMeshIndex == -1 {
+		for i := range mask {
+			mask[i] = true
+		}
+		return mask, nil
+	}
+	if subMeshIndex < 0 || subMeshIndex >= len(modelData.SubMeshes) {
+		return nil, fmt.Errorf("submesh %d out of range [0, %d)", subMeshIndex, len(modelData.SubMeshes))
+	}
+	for _, v := range modelData.SubMeshes[subMeshIndex] {
+		if v < 0 || int(v) >= len(mask) {
+			return nil, fmt.Errorf("submesh %d references vertex %d, but the model only has %d vertices", subMeshIndex, v, len(mask))
+		}
+		mask[v] = true
+	}
+	return mask, nil
+}
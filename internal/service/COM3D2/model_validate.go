@@ -0,0 +1,304 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 检查结果的严重程度
+const (
+	ModelFindingError   = "error"   // 很可能导致游戏崩溃或模型无法显示
+	ModelFindingWarning = "warning" // 可能导致显示异常
+)
+
+// 检查结果的类型
+const (
+	ModelFindingMissingRootBone         = "MissingRootBone"         // RootBoneName 为空或找不到对应骨骼
+	ModelFindingInvalidBoneParent       = "InvalidBoneParent"       // 骨骼的父骨骼索引越界或形成环
+	ModelFindingDuplicateBoneName       = "DuplicateBoneName"       // 骨骼重名
+	ModelFindingBoneCountMismatch       = "BoneCountMismatch"       // BoneCount 与 BoneNames 数量不一致
+	ModelFindingBindPoseCountMismatch   = "BindPoseCountMismatch"   // BindPoses 与 BoneNames 数量不一致
+	ModelFindingSkinBoneNotFound        = "SkinBoneNotFound"        // BoneNames 中的骨骼不在 Bones 中
+	ModelFindingVertexCountMismatch     = "VertexCountMismatch"     // VertCount、顶点、切线、权重数量不一致
+	ModelFindingSubMeshCountMismatch    = "SubMeshCountMismatch"    // SubMeshCount 与子网格数量不一致
+	ModelFindingMissingMaterial         = "MissingMaterial"         // 子网格没有对应的材质
+	ModelFindingBoneWeightOutOfRange    = "BoneWeightOutOfRange"    // 骨骼权重引用的骨骼索引超出 BoneCount
+	ModelFindingInvalidBoneWeight       = "InvalidBoneWeight"       // 骨骼权重为负数、NaN 或总和不为 1
+	ModelFindingInvalidVertex           = "InvalidVertex"           // 顶点位置或法线含有 NaN 或 Inf
+	ModelFindingZeroNormal              = "ZeroNormal"              // 法线长度为 0
+	ModelFindingEmptySubMesh            = "EmptySubMesh"            // 子网格没有三角形
+	ModelFindingInvalidSubMeshLength    = "InvalidSubMeshLength"    // 子网格索引数不是 3 的倍数
+	ModelFindingSubMeshIndexOutOfRange  = "SubMeshIndexOutOfRange"  // 子网格索引超出顶点数
+	ModelFindingDegenerateTriangle      = "DegenerateTriangle"      // 三角形有重复顶点或面积为 0
+	ModelFindingMorphIndexOutOfRange    = "MorphIndexOutOfRange"    // 形态键顶点索引超出顶点数
+	ModelFindingMorphDataLengthMismatch = "MorphDataLengthMismatch" // 形态键各数组长度不一致
+	ModelFindingInvalidMorphData        = "InvalidMorphData"        // 形态键偏移含有 NaN 或 Inf
+	ModelFindingDuplicateMorphName      = "DuplicateMorphName"      // 形态键重名
+)
+
+// 每种类型最多单独列出的检查结果数，超出部分会合并为一条
+const maxFindingsPerType = 50
+
+// ModelFinding 单条检查结果
+type ModelFinding struct {
+	Severity string `json:"Severity"` // 严重程度，见上方常量定义
+	Type     string `json:"Type"`     // 类型，见上方常量定义
+	Message  string `json:"Message"`  // 说明
+	Index    int    `json:"Index"`    // 相关元素的索引（骨骼、顶点、子网格、形态键等），没有时为 -1
+}
+
+// ModelValidationReport 模型检查报告
+type ModelValidationReport struct {
+	Findings     []ModelFinding `json:"Findings"`     // 检查结果
+	ErrorCount   int            `json:"ErrorCount"`   // 错误总数，包括被合并的条目
+	WarningCount int            `json:"WarningCount"` // 警告总数，包括被合并的条目
+}
+
+// ValidateModel 读取 .model 文件并检查数据完整性
+func (m *ModelService) ValidateModel(path string) (report ModelValidationReport, err error) {
+	modelData, err := m.ReadModelFile(path)
+	if err != nil {
+		return report, fmt.Errorf("failed to read model file: %w", err)
+	}
+	return validateModel(modelData), nil
+}
+
+// modelValidator 收集检查结果，并限制每种类型的条目数
+type modelValidator struct {
+	report     ModelValidationReport
+	typeCounts map[string]int
+	severities map[string]string
+}
+
+// add 添加一条检查结果
+func (v *modelValidator) add(severity string, findingType string, index int, format string, args ...any) {
+	if severity == ModelFindingError {
+		v.report.ErrorCount++
+	} else {
+		v.report.WarningCount++
+	}
+	v.typeCounts[findingType]++
+	v.severities[findingType] = severity
+	if v.typeCounts[findingType] > maxFindingsPerType {
+		return
+	}
+	v.report.Findings = append(v.report.Findings, ModelFinding{
+		Severity: severity,
+		Type:     findingType,
+		Message:  fmt.Sprintf(format, args...),
+		Index:    index,
+	})
+}
+
+// finish 为超出条目数上限的类型添加汇总条目
+func (v *modelValidator) finish() ModelValidationReport {
+	for _, f := range v.report.Findings[:len(v.report.Findings):len(v.report.Findings)] {
+		count := v.typeCounts[f.Type]
+		if count > maxFindingsPerType {
+			v.report.Findings = append(v.report.Findings, ModelFinding{
+				Severity: v.severities[f.Type],
+				Type:     f.Type,
+				Message:  fmt.Sprintf("%d more %s findings omitted", count-maxFindingsPerType, f.Type),
+				Index:    -1,
+			})
+			v.typeCounts[f.Type] = 0
+		}
+	}
+	if v.report.Findings == nil {
+		v.report.Findings = []ModelFinding{}
+	}
+	return v.report
+}
+
+// validateModel 检查模型数据完整性
+func validateModel(modelData *COM3D2.Model) ModelValidationReport {
+	v := &modelValidator{typeCounts: make(map[string]int), severities: make(map[string]string)}
+	validateModelBones(v, modelData)
+	validateModelVertices(v, modelData)
+	validateModelSubMeshes(v, modelData)
+	validateModelMorphs(v, modelData)
+	return v.finish()
+}
+
+// validateModelBones 检查骨骼、蒙皮骨骼和 BindPoses
+func validateModelBones(v *modelValidator, modelData *COM3D2.Model) {
+	if modelData.RootBoneName == "" {
+		v.add(ModelFindingError, ModelFindingMissingRootBone, -1, "RootBoneName is empty")
+	} else if findBone(modelData, modelData.RootBoneName) < 0 {
+		v.add(ModelFindingError, ModelFindingMissingRootBone, -1, "root bone %q not found in bones", modelData.RootBoneName)
+	}
+
+	seen := make(map[string]int, len(modelData.Bones))
+	for i, bone := range modelData.Bones {
+		if bone == nil {
+			v.add(ModelFindingError, ModelFindingInvalidBoneParent, i, "bone %d is null", i)
+			continue
+		}
+		if first, ok := seen[bone.Name]; ok {
+			v.add(ModelFindingWarning, ModelFindingDuplicateBoneName, i, "bone %d has the same name %q as bone %d", i, bone.Name, first)
+		} else {
+			seen[bone.Name] = i
+		}
+		parent := int(bone.ParentIndex)
+		if parent >= len(modelData.Bones) || parent == i || parent < -1 {
+			v.add(ModelFindingError, ModelFindingInvalidBoneParent, i, "bone %q has invalid parent index %d", bone.Name, parent)
+		} else if parent >= 0 && !slices.Contains(modelData.Bones, nil) && isBoneDescendant(modelData.Bones, parent, i) {
+			v.add(ModelFindingError, ModelFindingInvalidBoneParent, i, "bone %q is part of a parent cycle", bone.Name)
+		}
+	}
+
+	if int(modelData.BoneCount) != len(modelData.BoneNames) {
+		v.add(ModelFindingError, ModelFindingBoneCountMismatch, -1, "BoneCount is %d but there are %d bone names", modelData.BoneCount, len(modelData.BoneNames))
+	}
+	if len(modelData.BindPoses) != len(modelData.BoneNames) {
+		v.add(ModelFindingError, ModelFindingBindPoseCountMismatch, -1, "there are %d bind poses but %d bone names", len(modelData.BindPoses), len(modelData.BoneNames))
+	}
+	for i, name := range modelData.BoneNames {
+		if _, ok := seen[name]; !ok {
+			v.add(ModelFindingError, ModelFindingSkinBoneNotFound, i, "skin bone %d %q not found in bones", i, name)
+		}
+	}
+}
+
+// validateModelVertices 检查顶点、切线和骨骼权重
+func validateModelVertices(v *modelValidator, modelData *COM3D2.Model) {
+	vertexCount := len(modelData.Vertices)
+	if int(modelData.VertCount) != vertexCount {
+		v.add(ModelFindingError, ModelFindingVertexCountMismatch, -1, "VertCount is %d but there are %d vertices", modelData.VertCount, vertexCount)
+	}
+	if len(modelData.Tangents) != 0 && len(modelData.Tangents) != vertexCount {
+		v.add(ModelFindingError, ModelFindingVertexCountMismatch, -1, "there are %d tangents but %d vertices", len(modelData.Tangents), vertexCount)
+	}
+	if len(modelData.BoneWeights) != vertexCount {
+		v.add(ModelFindingError, ModelFindingVertexCountMismatch, -1, "there are %d bone weights but %d vertices", len(modelData.BoneWeights), vertexCount)
+	}
+
+	for i := range modelData.Vertices {
+		vertex := &modelData.Vertices[i]
+		if !isFiniteVec3(vertex.Position) {
+			v.add(ModelFindingError, ModelFindingInvalidVertex, i, "vertex %d has invalid position %v", i, vertex.Position)
+		}
+		if !isFiniteVec3(vertex.Normal) {
+			v.add(ModelFindingError, ModelFindingInvalidVertex, i, "vertex %d has invalid normal %v", i, vertex.Normal)
+		} else if vec3Length(vertex.Normal) == 0 {
+			v.add(ModelFindingWarning, ModelFindingZeroNormal, i, "vertex %d has a zero-length normal", i)
+		}
+	}
+
+	boneCount := int(modelData.BoneCount)
+	for i, bw := range modelData.BoneWeights {
+		var sum float32
+		for slot, inf := range getBoneInfluences(bw) {
+			if isNaNOrInf(inf.Weight) || inf.Weight < 0 {
+				v.add(ModelFindingError, ModelFindingInvalidBoneWeight, i, "vertex %d weight %d is invalid: %v", i, slot, inf.Weight)
+				continue
+			}
+			sum += inf.Weight
+			if inf.Index >= boneCount {
+				if inf.Weight > 0 {
+					v.add(ModelFindingError, ModelFindingBoneWeightOutOfRange, i, "vertex %d weight %d references bone %d, but BoneCount is %d", i, slot, inf.Index, boneCount)
+				} else {
+					v.add(ModelFindingWarning, ModelFindingBoneWeightOutOfRange, i, "vertex %d weight %d references bone %d with zero weight, but BoneCount is %d", i, slot, inf.Index, boneCount)
+				}
+			}
+		}
+		if math.Abs(float64(sum-1)) > 0.01 {
+			v.add(ModelFindingWarning, ModelFindingInvalidBoneWeight, i, "vertex %d weights sum to %v instead of 1", i, sum)
+		}
+	}
+}
+
+// validateModelSubMeshes 检查子网格和材质
+func validateModelSubMeshes(v *modelValidator, modelData *COM3D2.Model) {
+	if int(modelData.SubMeshCount) != len(modelData.SubMeshes) {
+		v.add(ModelFindingError, ModelFindingSubMeshCountMismatch, -1, "SubMeshCount is %d but there are %d submeshes", modelData.SubMeshCount, len(modelData.SubMeshes))
+	}
+	for i := range modelData.SubMeshes {
+		if i >= len(modelData.Materials) || modelData.Materials[i] == nil {
+			v.add(ModelFindingWarning, ModelFindingMissingMaterial, i, "submesh %d has no material", i)
+		}
+	}
+
+	vertexCount := len(modelData.Vertices)
+	for i, subMesh := range modelData.SubMeshes {
+		if len(subMesh) == 0 {
+			v.add(ModelFindingWarning, ModelFindingEmptySubMesh, i, "submesh %d is empty", i)
+			continue
+		}
+		if len(subMesh)%3 != 0 {
+			v.add(ModelFindingError, ModelFindingInvalidSubMeshLength, i, "submesh %d has %d indices, which is not a multiple of 3", i, len(subMesh))
+		}
+		for t := 0; t+2 < len(subMesh); t += 3 {
+			a, b, c := int(subMesh[t]), int(subMesh[t+1]), int(subMesh[t+2])
+			outOfRange := false
+			for _, index := range [3]int{a, b, c} {
+				if index < 0 || index >= vertexCount {
+					v.add(ModelFindingError, ModelFindingSubMeshIndexOutOfRange, i, "submesh %d triangle %d references vertex %d, but there are %d vertices", i, t/3, index, vertexCount)
+					outOfRange = true
+				}
+			}
+			if outOfRange {
+				continue
+			}
+			if a == b || b == c || a == c {
+				v.add(ModelFindingWarning, ModelFindingDegenerateTriangle, i, "submesh %d triangle %d has repeated vertices (%d, %d, %d)", i, t/3, a, b, c)
+				continue
+			}
+			pa, pb, pc := modelData.Vertices[a].Position, modelData.Vertices[b].Position, modelData.Vertices[c].Position
+			if vec3Length(vec3Cross(vec3Sub(pb, pa), vec3Sub(pc, pa))) == 0 {
+				v.add(ModelFindingWarning, ModelFindingDegenerateTriangle, i, "submesh %d triangle %d has zero area", i, t/3)
+			}
+		}
+	}
+}
+
+// validateModelMorphs 检查形态键
+func validateModelMorphs(v *modelValidator, modelData *COM3D2.Model) {
+	vertexCount := len(modelData.Vertices)
+	seen := make(map[string]int, len(modelData.MorphData))
+	for i, morph := range modelData.MorphData {
+		if morph == nil {
+			v.add(ModelFindingError, ModelFindingMorphDataLengthMismatch, i, "morph %d is null", i)
+			continue
+		}
+		if first, ok := seen[morph.Name]; ok {
+			v.add(ModelFindingWarning, ModelFindingDuplicateMorphName, i, "morph %d has the same name %q as morph %d", i, morph.Name, first)
+		} else {
+			seen[morph.Name] = i
+		}
+
+		n := len(morph.Indices)
+		if len(morph.Vertex) != n || len(morph.Normals) != n || (len(morph.Tangents) != 0 && len(morph.Tangents) != n) {
+			v.add(ModelFindingError, ModelFindingMorphDataLengthMismatch, i, "morph %q has %d indices, %d vertex offsets, %d normal offsets and %d tangents",
+				morph.Name, n, len(morph.Vertex), len(morph.Normals), len(morph.Tangents))
+		}
+		for j, index := range morph.Indices {
+			if int(index) < 0 || int(index) >= vertexCount {
+				v.add(ModelFindingError, ModelFindingMorphIndexOutOfRange, i, "morph %q entry %d references vertex %d, but there are %d vertices", morph.Name, j, index, vertexCount)
+			}
+		}
+		for j, d := range morph.Vertex {
+			if !isFiniteVec3(d) {
+				v.add(ModelFindingError, ModelFindingInvalidMorphData, i, "morph %q entry %d has invalid vertex offset %v", morph.Name, j, d)
+			}
+		}
+		for j, d := range morph.Normals {
+			if !isFiniteVec3(d) {
+				v.add(ModelFindingError, ModelFindingInvalidMorphData, i, "morph %q entry %d has invalid normal offset %v", morph.Name, j, d)
+			}
+		}
+	}
+}
+
+// isNaNOrInf 判断是否为 NaN 或 Inf
+func isNaNOrInf(f float32) bool {
+	return math.IsNaN(float64(f)) || math.IsInf(float64(f), 0)
+}
+
+// isFiniteVec3 判断向量的各分量是否都是有限值
+func isFiniteVec3(a COM3D2.Vector3) bool {
+	return !isNaNOrInf(a.X) && !isNaNOrInf(a.Y) && !isNaNOrInf(a.Z)
+}
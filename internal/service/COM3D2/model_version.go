@@ -0,0 +1,206 @@
+package COM3D2
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 可以转换到的 .model 版本
+var supportedModelVersions = []int32{1000, 2000, 2001, 2100, 2101, 2102, 2103, 2104, 2200}
+
+// .model 各版本引入的数据特性
+const (
+	ModelFeatureSkinThickness     = "SkinThickness"     // 皮肤厚度数据
+	ModelFeatureBoneScale         = "BoneScale"         // 骨骼缩放
+	ModelFeatureExtraUV           = "ExtraUV"           // UV2-UV4 及额外的顶点数据
+	ModelFeatureMorphTangents     = "MorphTangents"     // 形态键切线
+	ModelFeatureShadowCastingMode = "ShadowCastingMode" // 阴影投射模式
+)
+
+// modelFeatureMinVersion 每个特性所需的最低版本
+var modelFeatureMinVersion = []struct {
+	Feature    string
+	MinVersion int32
+}{
+	{ModelFeatureSkinThickness, 2000},
+	{ModelFeatureBoneScale, 2001},
+	{ModelFeatureExtraUV, 2101},
+	{ModelFeatureMorphTangents, 2102},
+	{ModelFeatureShadowCastingMode, 2200},
+}
+
+// ModelFeatureUsage 单个版本特性的使用情况
+type ModelFeatureUsage struct {
+	Feature    string `json:"Feature"`    // 特性名，见上方常量定义
+	MinVersion int32  `json:"MinVersion"` // 需要的最低版本
+	Supported  bool   `json:"Supported"`  // 当前文件版本是否支持该特性
+	Used       bool   `json:"Used"`       // 文件是否实际使用了该特性
+}
+
+// ModelVersionReport 模型版本分析结果
+type ModelVersionReport struct {
+	Version         int32               `json:"Version"`         // 文件版本
+	MinimumVersion  int32               `json:"MinimumVersion"`  // 能够无损保存当前数据的最低版本
+	Features        []ModelFeatureUsage `json:"Features"`        // 各特性的使用情况
+	SupportedTarget []int32             `json:"SupportedTarget"` // 可以转换到的版本
+}
+
+// ModelVersionConvertResult 模型版本转换结果
+type ModelVersionConvertResult struct {
+	FromVersion int32    `json:"FromVersion"` // 原版本
+	ToVersion   int32    `json:"ToVersion"`   // 目标版本
+	Warnings    []string `json:"Warnings"`    // 丢弃或补全数据时的警告
+}
+
+// AnalyzeModelVersion 读取 .model 文件，报告其使用了哪些版本相关的数据
+func (m *ModelService) AnalyzeModelVersion(path string) (report ModelVersionReport, err error) {
+	modelData, err := m.ReadModelFile(path)
+	if err != nil {
+		return report, fmt.Errorf("failed to read model file: %w", err)
+	}
+	return analyzeModelVersion(modelData), nil
+}
+
+// ConvertModelVersion 将 .model 文件转换为 targetVersion 版本并写入 outputPath
+// 降级时会丢弃目标版本不支持的数据，升级时会在需要时补全数据，所有改动都会以警告的形式返回
+func (m *ModelService) ConvertModelVersion(inputPath string, outputPath string, targetVersion int32) (result ModelVersionConvertResult, err error) {
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = convertModelVersion(modelData, targetVersion)
+		return err
+	})
+	return result, err
+}
+
+// analyzeModelVersion 分析模型实际使用的版本特性
+func analyzeModelVersion(modelData *COM3D2.Model) ModelVersionReport {
+	report := ModelVersionReport{
+		Version:         modelData.Version,
+		MinimumVersion:  supportedModelVersions[0],
+		SupportedTarget: supportedModelVersions,
+	}
+	used := modelFeaturesUsed(modelData)
+	for _, f := range modelFeatureMinVersion {
+		usage := ModelFeatureUsage{
+			Feature:    f.Feature,
+			MinVersion: f.MinVersion,
+			Supported:  modelData.Version >= f.MinVersion,
+			Used:       used[f.Feature],
+		}
+		if usage.Used && f.MinVersion > report.MinimumVersion {
+			report.MinimumVersion = f.MinVersion
+		}
+		report.Features = append(report.Features, usage)
+	}
+	return report
+}
+
+// modelFeaturesUsed 返回模型实际使用的版本特性
+func modelFeaturesUsed(modelData *COM3D2.Model) map[string]bool {
+	used := make(map[string]bool, len(modelFeatureMinVersion))
+	used[ModelFeatureSkinThickness] = modelData.SkinThickness != nil
+	used[ModelFeatureShadowCastingMode] = modelData.ShadowCastingMode != nil
+	for _, bone := range modelData.Bones {
+		if bone.HasScale {
+			used[ModelFeatureBoneScale] = true
+			break
+		}
+	}
+	for i := range modelData.Vertices {
+		v := &modelData.Vertices[i]
+		if v.UV2 != nil || v.UV3 != nil || v.UV4 != nil || v.Unknown1 != nil || v.Unknown2 != nil || v.Unknown3 != nil || v.Unknown4 != nil {
+			used[ModelFeatureExtraUV] = true
+			break
+		}
+	}
+	used[ModelFeatureMorphTangents] = morphsUseTangents(modelData)
+	return used
+}
+
+// convertModelVersion 就地转换模型版本
+func convertModelVersion(modelData *COM3D2.Model, targetVersion int32) (result ModelVersionConvertResult, err error) {
+	if !slices.Contains(supportedModelVersions, targetVersion) {
+		return result, fmt.Errorf("unsupported target model version %d, supported versions: %v", targetVersion, supportedModelVersions)
+	}
+	result.FromVersion = modelData.Version
+	result.ToVersion = targetVersion
+	result.Warnings = []string{}
+	warn := func(format string, args ...any) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	// 降级：丢弃目标版本不支持的数据
+	if targetVersion < 2000 && modelData.SkinThickness != nil {
+		warn("SkinThickness dropped: requires version >= 2000")
+		modelData.SkinThickness = nil
+	}
+
+	if targetVersion < 2001 {
+		var scaled []string
+		for i := range modelData.Bones {
+			bone := modelData.Bones[i]
+			if !bone.HasScale {
+				continue
+			}
+			if s := boneLocalScale(bone); s.X != 1 || s.Y != 1 || s.Z != 1 {
+				scaled = append(scaled, bone.Name)
+			}
+			modelData.Bones[i].HasScale = false
+			modelData.Bones[i].Scale = nil
+		}
+		if len(scaled) > 0 {
+			warn("bone scale dropped for %d bones with non-unit scale (requires version >= 2001): %v", len(scaled), scaled)
+		}
+	}
+
+	if targetVersion < 2101 {
+		dropped := 0
+		for i := range modelData.Vertices {
+			v := &modelData.Vertices[i]
+			if v.UV2 != nil || v.UV3 != nil || v.UV4 != nil || v.Unknown1 != nil || v.Unknown2 != nil || v.Unknown3 != nil || v.Unknown4 != nil {
+				dropped++
+			}
+			v.UV2, v.UV3, v.UV4 = nil, nil, nil
+			v.Unknown1, v.Unknown2, v.Unknown3, v.Unknown4 = nil, nil, nil, nil
+		}
+		if dropped > 0 {
+			warn("extra UV data dropped on %d vertices: requires version >= 2101", dropped)
+		}
+	}
+
+	if targetVersion < 2102 {
+		if morphsUseTangents(modelData) {
+			warn("morph tangents dropped: requires version >= 2102")
+		}
+		for _, morph := range modelData.MorphData {
+			morph.Tangents = nil
+		}
+	} else {
+		// 升级：该版本的形态键需要切线数据，没有时以 0 补全
+		filled := 0
+		for _, morph := range modelData.MorphData {
+			if len(morph.Tangents) != len(morph.Indices) {
+				morph.Tangents = make([]COM3D2.Quaternion, len(morph.Indices))
+				filled++
+			}
+		}
+		if filled > 0 {
+			warn("morph tangents synthesised as zero for %d morphs", filled)
+		}
+	}
+
+	if targetVersion < 2200 {
+		if modelData.ShadowCastingMode != nil {
+			warn("ShadowCastingMode %q dropped: requires version >= 2200", *modelData.ShadowCastingMode)
+		}
+		modelData.ShadowCastingMode = nil
+	} else if modelData.ShadowCastingMode == nil {
+		mode := "On"
+		modelData.ShadowCastingMode = &mode
+		warn("ShadowCastingMode synthesised as %q", mode)
+	}
+
+	modelData.Version = targetVersion
+	return result, nil
+}
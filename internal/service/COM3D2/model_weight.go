@@ -0,0 +1,263 @@
+package COM3D2
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// weightEpsilon 判断权重是否发生变化时使用的误差
+const weightEpsilon = 1e-6
+
+// BoneWeightCleanupOptions 骨骼权重清理选项
+type BoneWeightCleanupOptions struct {
+	Normalize      bool     `json:"Normalize"`      // 是否将每个顶点的权重之和归一化为 1
+	PruneThreshold float32  `json:"PruneThreshold"` // 小于该值的权重会被移除，0 表示不移除
+	MaxInfluences  int      `json:"MaxInfluences"`  // 每个顶点最多保留几个骨骼影响（1-4），0 表示不限制
+	LockedBones    []string `json:"LockedBones"`    // 需要清零的骨骼名，其权重会转移到最近的未锁定父骨骼
+}
+
+// BoneWeightCleanupResult 骨骼权重清理结果
+type BoneWeightCleanupResult struct {
+	TotalVertexCount      int `json:"TotalVertexCount"`      // 顶点总数
+	ChangedVertexCount    int `json:"ChangedVertexCount"`    // 权重发生变化的顶点数
+	UnresolvedVertexCount int `json:"UnresolvedVertexCount"` // 权重位于锁定骨骼上，但找不到可以转移的骨骼的顶点数
+}
+
+// boneInfluence 单个骨骼对顶点的影响
+type boneInfluence struct {
+	Index  int
+	Weight float32
+}
+
+// getBoneInfluences 将 BoneWeight 展开为数组，方便统一处理
+func getBoneInfluences(bw COM3D2.BoneWeight) [4]boneInfluence {
+	return [4]boneInfluence{
+		{Index: int(bw.BoneIndex0), Weight: bw.Weight0},
+		{Index: int(bw.BoneIndex1), Weight: bw.Weight1},
+		{Index: int(bw.BoneIndex2), Weight: bw.Weight2},
+		{Index: int(bw.BoneIndex3), Weight: bw.Weight3},
+	}
+}
+
+// setBoneInfluences 将骨骼影响写回 BoneWeight，超出 4 个的部分会被丢弃，不足的部分用 0 填充
+func setBoneInfluences(bw *COM3D2.BoneWeight, influences []boneInfluence) {
+	var packed [4]boneInfluence
+	copy(packed[:], influences)
+	bw.BoneIndex0, bw.Weight0 = uint16(packed[0].Index), packed[0].Weight
+	bw.BoneIndex1, bw.Weight1 = uint16(packed[1].Index), packed[1].Weight
+	bw.BoneIndex2, bw.Weight2 = uint16(packed[2].Index), packed[2].Weight
+	bw.BoneIndex3, bw.Weight3 = uint16(packed[3].Index), packed[3].Weight
+}
+
+// mergeBoneInfluences 合并同一骨骼的影响，去掉权重为 0 的项，并按权重从大到小排序
+func mergeBoneInfluences(influences []boneInfluence) []boneInfluence {
+	merged := make([]boneInfluence, 0, len(influences))
+	for _, inf := range influences {
+		if inf.Weight <= 0 {
+			continue
+		}
+		found := false
+		for i := range merged {
+			if merged[i].Index == inf.Index {
+				merged[i].Weight += inf.Weight
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, inf)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Weight > merged[j].Weight
+	})
+	return merged
+}
+
+// normalizeBoneInfluences 将权重之和归一化为 1
+func normalizeBoneInfluences(influences []boneInfluence) {
+	var sum float32
+	for _, inf := range influences {
+		sum += inf.Weight
+	}
+	if sum <= 0 {
+		return
+	}
+	for i := range influences {
+		influences[i].Weight /= sum
+	}
+}
+
+// boneWeightChanged 判断两个 BoneWeight 是否不同，权重为 0 的骨骼索引不参与比较
+func boneWeightChanged(a, b COM3D2.BoneWeight) bool {
+	ia, ib := getBoneInfluences(a), getBoneInfluences(b)
+	for i := range ia {
+		wa, wb := ia[i].Weight, ib[i].Weight
+		if wa-wb > weightEpsilon || wb-wa > weightEpsilon {
+			return true
+		}
+		if wa != 0 && ia[i].Index != ib[i].Index {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanupBoneWeights 读取 .model 文件，清理骨骼权重后写入 outputPath，可以为相同路径
+// 处理顺序为：锁定骨骼的权重转移到父骨骼 -> 移除过小的权重 -> 限制影响数量 -> 归一化
+func (m *ModelService) CleanupBoneWeights(inputPath string, outputPath string, options BoneWeightCleanupOptions) (result BoneWeightCleanupResult, err error) {
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	result, err = cleanupBoneWeights(modelData, options)
+	if err != nil {
+		return result, err
+	}
+
+	return result, m.WriteModelFile(outputPath, modelData)
+}
+
+// cleanupBoneWeights 就地清理模型的骨骼权重
+func cleanupBoneWeights(modelData *COM3D2.Model, options BoneWeightCleanupOptions) (result BoneWeightCleanupResult, err error) {
+	if options.MaxInfluences < 0 || options.MaxInfluences > 4 {
+		return result, fmt.Errorf("max influences must be between 0 and 4, got %d", options.MaxInfluences)
+	}
+	if options.PruneThreshold < 0 || options.PruneThreshold >= 1 {
+		return result, fmt.Errorf("prune threshold must be in [0, 1), got %v", options.PruneThreshold)
+	}
+
+	redirect, err := lockedBoneRedirects(modelData, options.LockedBones)
+	if err != nil {
+		return result, err
+	}
+
+	result.TotalVertexCount = len(modelData.BoneWeights)
+	for i := range modelData.BoneWeights {
+		original := modelData.BoneWeights[i]
+		raw := getBoneInfluences(original)
+
+		// 锁定骨骼的权重转移到父骨骼，如果没有可用的父骨骼，则分摊给该顶点的其他骨骼
+		unresolved := false
+		var lockedWeight float32
+		for j := range raw {
+			if raw[j].Weight <= 0 {
+				continue
+			}
+			target, locked := redirect[raw[j].Index]
+			if !locked {
+				continue
+			}
+			if target >= 0 {
+				raw[j].Index = target
+			} else {
+				lockedWeight += raw[j].Weight
+				raw[j].Weight = 0
+			}
+		}
+		influences := mergeBoneInfluences(raw[:])
+		if lockedWeight > 0 {
+			if len(influences) == 0 {
+				// 没有其他骨骼可以承接，保持原样
+				originalInfluences := getBoneInfluences(original)
+				influences = mergeBoneInfluences(originalInfluences[:])
+				unresolved = true
+			} else {
+				var rest float32
+				for _, inf := range influences {
+					rest += inf.Weight
+				}
+				for j := range influences {
+					influences[j].Weight += lockedWeight * influences[j].Weight / rest
+				}
+			}
+		}
+
+		// 移除过小的权重，但至少保留权重最大的一项
+		if options.PruneThreshold > 0 {
+			kept := influences[:0]
+			for j, inf := range influences {
+				if j == 0 || inf.Weight >= options.PruneThreshold {
+					kept = append(kept, inf)
+				}
+			}
+			influences = kept
+		}
+
+		if options.MaxInfluences > 0 && len(influences) > options.MaxInfluences {
+			influences = influences[:options.MaxInfluences]
+		}
+
+		if options.Normalize {
+			normalizeBoneInfluences(influences)
+		}
+
+		setBoneInfluences(&modelData.BoneWeights[i], influences)
+		if unresolved {
+			result.UnresolvedVertexCount++
+		}
+		if boneWeightChanged(original, modelData.BoneWeights[i]) {
+			result.ChangedVertexCount++
+		}
+	}
+
+	return result, nil
+}
+
+// lockedBoneRedirects 计算锁定骨骼（BoneNames 中的索引）应转移到的目标索引
+// 目标为沿 Bones 层级向上查找到的第一个位于 BoneNames 中且未被锁定的骨骼，找不到时为 -1
+func lockedBoneRedirects(modelData *COM3D2.Model, lockedBones []string) (map[int]int, error) {
+	redirect := make(map[int]int, len(lockedBones))
+	if len(lockedBones) == 0 {
+		return redirect, nil
+	}
+
+	locked := make(map[string]struct{}, len(lockedBones))
+	for _, name := range lockedBones {
+		locked[name] = struct{}{}
+	}
+
+	skinIndex := make(map[string]int, len(modelData.BoneNames))
+	for i, name := range modelData.BoneNames {
+		if _, exists := skinIndex[name]; !exists {
+			skinIndex[name] = i
+		}
+	}
+
+	boneIndex := make(map[string]int, len(modelData.Bones))
+	for i, bone := range modelData.Bones {
+		if _, exists := boneIndex[bone.Name]; !exists {
+			boneIndex[bone.Name] = i
+		}
+	}
+
+	for _, name := range lockedBones {
+		idx, ok := skinIndex[name]
+		if !ok {
+			return nil, fmt.Errorf("locked bone %q is not a skinning bone of this model", name)
+		}
+
+		target := -1
+		current, ok := boneIndex[name]
+		for steps := 0; ok && steps < len(modelData.Bones); steps++ {
+			parent := int(modelData.Bones[current].ParentIndex)
+			if parent < 0 || parent >= len(modelData.Bones) {
+				break
+			}
+			parentName := modelData.Bones[parent].Name
+			if parentSkin, isSkin := skinIndex[parentName]; isSkin {
+				if _, isLocked := locked[parentName]; !isLocked {
+					target = parentSkin
+					break
+				}
+			}
+			current = parent
+		}
+		redirect[idx] = target
+	}
+
+	return redirect, nil
+}
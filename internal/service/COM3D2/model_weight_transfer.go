@@ -0,0 +1,267 @@
+package COM3D2
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// 权重传递的默认最大距离，单位与模型坐标一致（游戏中 1 约为 1 米）
+const defaultWeightTransferDistance = 0.05
+
+// WeightTransferOptions 从参考模型传递骨骼权重的选项
+type WeightTransferOptions struct {
+	SubMeshIndex   int     `json:"SubMeshIndex"`   // 只处理该子网格的顶点，-1 表示所有顶点
+	MaxDistance    float32 `json:"MaxDistance"`    // 超过该距离的顶点不传递权重，保留原权重，0 时使用默认值 0.05
+	FalloffStart   float32 `json:"FalloffStart"`   // 距离超过该值后，传递的权重与原权重线性混合，到 MaxDistance 时完全为原权重，0 表示不混合
+	PruneThreshold float32 `json:"PruneThreshold"` // 传递后小于该值的权重会被移除
+}
+
+// WeightTransferResult 传递骨骼权重的结果
+type WeightTransferResult struct {
+	TransferredCount int      `json:"TransferredCount"` // 完全使用参考模型权重的顶点数
+	BlendedCount     int      `json:"BlendedCount"`     // 与原权重混合的顶点数
+	FarVertices      []int    `json:"FarVertices"`      // 距离参考模型表面太远、未传递权重的顶点
+	PrunedVertices   []int    `json:"PrunedVertices"`   // 所有权重都小于 PruneThreshold 被移除、保留原权重的顶点
+	AddedBones       []string `json:"AddedBones"`       // 为了容纳参考模型的权重而新增的骨骼
+}
+
+// TransferBoneWeights 从参考模型（一般为身体）的最近表面点插值骨骼权重，写入目标模型的 outputPath
+// 两个模型需要位于相同的网格空间，目标模型缺少的骨骼会从参考模型复制
+func (m *ModelService) TransferBoneWeights(inputPath string, referencePath string, outputPath string, options WeightTransferOptions) (result WeightTransferResult, err error) {
+	reference, err := m.ReadModelFile(referencePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read reference model file: %w", err)
+	}
+	err = m.editModel(inputPath, outputPath, func(modelData *COM3D2.Model) error {
+		result, err = transferBoneWeights(modelData, reference, options)
+		return err
+	})
+	return result, err
+}
+
+// transferBoneWeights 从 reference 向 modelData 传递骨骼权重
+func transferBoneWeights(modelData *COM3D2.Model, reference *COM3D2.Model, options WeightTransferOptions) (result WeightTransferResult, err error) {
+	maxDistance := options.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = defaultWeightTransferDistance
+	}
+	if options.FalloffStart < 0 || options.FalloffStart >= maxDistance {
+		options.FalloffStart = 0
+	}
+	if len(reference.BoneWeights) != len(reference.Vertices) {
+		return result, fmt.Errorf("reference model has %d bone weights but %d vertices", len(reference.BoneWeights), len(reference.Vertices))
+	}
+	if len(modelData.BoneWeights) != len(modelData.Vertices) {
+		return result, fmt.Errorf("target model has %d bone weights but %d vertices", len(modelData.BoneWeights), len(modelData.Vertices))
+	}
+
+	selected, err := subMeshVertexMask(modelData, options.SubMeshIndex)
+	if err != nil {
+		return result, err
+	}
+	triangles, err := collectTriangles(reference, -1)
+	if err != nil {
+		return result, fmt.Errorf("invalid reference model: %w", err)
+	}
+	if len(triangles) == 0 {
+		return result, fmt.Errorf("reference model has no triangles")
+	}
+
+	referencePositions := vertexPositions(reference)
+	grid := newTriangleGrid(referencePositions, triangles, maxDistance)
+	skinRemap := make(map[int]int)
+	result.FarVertices = []int{}
+	result.PrunedVertices = []int{}
+
+	for v, ok := range selected {
+		if !ok {
+			continue
+		}
+		position := modelData.Vertices[v].Position
+		tri, bary, distance := grid.nearest(position, maxDistance)
+		if tri < 0 {
+			result.FarVertices = append(result.FarVertices, v)
+			continue
+		}
+
+		// 按重心坐标插值三个角的权重
+		var influences []boneInfluence
+		for corner, vertexIndex := range triangles[tri] {
+			for _, inf := range getBoneInfluences(reference.BoneWeights[vertexIndex]) {
+				if inf.Weight <= 0 {
+					continue
+				}
+				targetIndex, err := mapReferenceSkinBone(modelData, reference, inf.Index, skinRemap, &result)
+				if err != nil {
+					return result, err
+				}
+				influences = append(influences, boneInfluence{Index: targetIndex, Weight: inf.Weight * bary[corner]})
+			}
+		}
+
+		// 衰减区间内与原权重混合
+		blended := options.FalloffStart > 0 && distance > options.FalloffStart
+		if blended {
+			t := (distance - options.FalloffStart) / (maxDistance - options.FalloffStart)
+			for i := range influences {
+				influences[i].Weight *= 1 - t
+			}
+			for _, inf := range getBoneInfluences(modelData.BoneWeights[v]) {
+				if inf.Weight > 0 {
+					influences = append(influences, boneInfluence{Index: inf.Index, Weight: inf.Weight * t})
+				}
+			}
+		}
+
+		influences = mergeBoneInfluences(influences)
+		if options.PruneThreshold > 0 {
+			influences = slices.DeleteFunc(influences, func(inf boneInfluence) bool {
+				return inf.Weight < options.PruneThreshold
+			})
+		}
+		if len(influences) > 4 {
+			influences = influences[:4]
+		}
+		normalizeBoneInfluences(influences)
+		if len(influences) == 0 {
+			result.PrunedVertices = append(result.PrunedVertices, v)
+			continue
+		}
+		setBoneInfluences(&modelData.BoneWeights[v], influences)
+		if blended {
+			result.BlendedCount++
+		} else {
+			result.TransferredCount++
+		}
+	}
+
+	modelData.BoneCount = int32(len(modelData.BoneNames))
+	return result, nil
+}
+
+// mapReferenceSkinBone 将参考模型的蒙皮骨骼索引映射到目标模型，目标模型没有时添加该骨骼和对应的 BindPose
+func mapReferenceSkinBone(modelData *COM3D2.Model, reference *COM3D2.Model, skinIndex int, skinRemap map[int]int, result *WeightTransferResult) (int, error) {
+	if mapped, ok := skinRemap[skinIndex]; ok {
+		return mapped, nil
+	}
+	if skinIndex >= len(reference.BoneNames) || skinIndex >= len(reference.BindPoses) {
+		return 0, fmt.Errorf("reference bone weight references skin bone %d, but the reference model only has %d", skinIndex, len(reference.BoneNames))
+	}
+	name := reference.BoneNames[skinIndex]
+	mapped := slices.Index(modelData.BoneNames, name)
+	if mapped < 0 {
+		if len(modelData.BoneNames) >= 0xFFFF {
+			return 0, fmt.Errorf("target model already has %d skin bones, cannot add %q", len(modelData.BoneNames), name)
+		}
+		if err := copyBoneFromReference(modelData, reference, name, result); err != nil {
+			return 0, err
+		}
+		mapped = len(modelData.BoneNames)
+		modelData.BoneNames = append(modelData.BoneNames, name)
+		modelData.BindPoses = append(modelData.BindPoses, reference.BindPoses[skinIndex])
+	}
+	skinRemap[skinIndex] = mapped
+	return mapped, nil
+}
+
+// copyBoneFromReference 目标模型没有该骨骼时，从参考模型复制该骨骼及缺少的父骨骼，局部变换保持不变
+func copyBoneFromReference(modelData *COM3D2.Model, reference *COM3D2.Model, name string, result *WeightTransferResult) error {
+	if findBone(modelData, name) >= 0 {
+		return nil
+	}
+	referenceIndex := findBone(reference, name)
+	if referenceIndex < 0 {
+		return fmt.Errorf("skin bone %q not found in reference model bones", name)
+	}
+	bone := reference.Bones[referenceIndex]
+	parentIndex := int32(-1)
+	if parent := int(bone.ParentIndex); parent >= 0 && parent < len(reference.Bones) && !isBoneDescendant(reference.Bones, parent, referenceIndex) {
+		parentName := reference.Bones[parent].Name
+		if err := copyBoneFromReference(modelData, reference, parentName, result); err != nil {
+			return err
+		}
+		parentIndex = int32(findBone(modelData, parentName))
+	}
+	copied := &COM3D2.Bone{
+		Name:        bone.Name,
+		HasScale:    bone.HasScale,
+		ParentIndex: parentIndex,
+		Position:    bone.Position,
+		Rotation:    bone.Rotation,
+	}
+	if bone.Scale != nil {
+		scale := *bone.Scale
+		copied.Scale = &scale
+	}
+	modelData.Bones = append(modelData.Bones, copied)
+	result.AddedBones = append(result.AddedBones, bone.Name)
+	return nil
+}
+
+// triangleGrid 按包围盒对三角形进行网格划分，用于查找离某个位置最近的表面点
+type triangleGrid struct {
+	cellSize  float32
+	cells     map[[3]int32][]int
+	positions []COM3D2.Vector3
+	triangles [][3]int
+}
+
+// newTriangleGrid 建立三角形索引，cellSize 一般取查找半径
+func newTriangleGrid(positions []COM3D2.Vector3, triangles [][3]int, cellSize float32) *triangleGrid {
+	g := &triangleGrid{
+		cellSize:  cellSize,
+		cells:     make(map[[3]int32][]int, len(triangles)),
+		positions: positions,
+		triangles: triangles,
+	}
+	for t, tri := range triangles {
+		a, b, c := positions[tri[0]], positions[tri[1]], positions[tri[2]]
+		lo := gridCell(COM3D2.Vector3{X: min(a.X, b.X, c.X), Y: min(a.Y, b.Y, c.Y), Z: min(a.Z, b.Z, c.Z)}, cellSize)
+		hi := gridCell(COM3D2.Vector3{X: max(a.X, b.X, c.X), Y: max(a.Y, b.Y, c.Y), Z: max(a.Z, b.Z, c.Z)}, cellSize)
+		for x := lo[0]; x <= hi[0]; x++ {
+			for y := lo[1]; y <= hi[1]; y++ {
+				for z := lo[2]; z <= hi[2]; z++ {
+					key := [3]int32{x, y, z}
+					g.cells[key] = append(g.cells[key], t)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// nearest 返回距离 p 不超过 maxDistance 的最近三角形、最近点的重心坐标和距离，没有时三角形为 -1
+// maxDistance 不应大于 cellSize，否则可能漏找
+func (g *triangleGrid) nearest(p COM3D2.Vector3, maxDistance float32) (int, [3]float32, float32) {
+	best := -1
+	var bestBary [3]float32
+	bestDist := maxDistance
+	center := gridCell(p, g.cellSize)
+	visited := make(map[int]struct{})
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dz := int32(-1); dz <= 1; dz++ {
+				for _, t := range g.cells[[3]int32{center[0] + dx, center[1] + dy, center[2] + dz}] {
+					if _, ok := visited[t]; ok {
+						continue
+					}
+					visited[t] = struct{}{}
+					tri := g.triangles[t]
+					a, b, c := g.positions[tri[0]], g.positions[tri[1]], g.positions[tri[2]]
+					bary := closestPointOnTriangle(p, a, b, c)
+					dist := vec3Length(vec3Sub(barycentricPoint(bary, a, b, c), p))
+					if dist <= bestDist {
+						best, bestBary, bestDist = t, bary, dist
+					}
+				}
+			}
+		}
+	}
+	if best < 0 {
+		return -1, bestBary, float32(math.Inf(1))
+	}
+	return best, bestBary, bestDist
+}
@@ -0,0 +1,136 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // 注册 JPG 解码器，ARGB32/RGB24 的 .tex 数据位可能是 JPG
+	_ "image/png"
+
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// .tex 中 TextureFormat 的取值，与 Unity 的 TextureFormat 一致
+const (
+	texFormatDXT1 = 10
+	texFormatDXT5 = 12
+)
+
+// ddsHeaderSize DDS 文件头（包括 "DDS " 魔数）的长度
+const ddsHeaderSize = 128
+
+// decodeTexNative 不依赖 ImageMagick 解码 .tex
+// 数据位为 PNG 或 JPG 时直接解码，DXT1 和 DXT5 按块解压，其他格式返回 ok 为 false
+func decodeTexNative(tex *COM3D2.Tex) (img image.Image, ok bool, err error) {
+	data := tex.Data
+	if bytes.HasPrefix(data, []byte("\x89PNG")) || bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}) {
+		img, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to decode tex image data: %w", err)
+		}
+		return img, true, nil
+	}
+	if tex.TextureFormat != texFormatDXT1 && tex.TextureFormat != texFormatDXT5 {
+		return nil, false, nil
+	}
+	if bytes.HasPrefix(data, []byte("DDS ")) && len(data) >= ddsHeaderSize {
+		data = data[ddsHeaderSize:]
+	}
+	img, err = decodeDXT(data, int(tex.Width), int(tex.Height), tex.TextureFormat == texFormatDXT5)
+	return img, true, err
+}
+
+// decodeDXT 解压 DXT1 或 DXT5 数据的第一层 mipmap
+// Unity 的纹理数据从最下面一行开始存储，解压后会上下翻转，使其与 PNG 的方向一致
+func decodeDXT(data []byte, width, height int, dxt5 bool) (*image.NRGBA, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid dxt texture size %dx%d", width, height)
+	}
+	blockSize := 8
+	if dxt5 {
+		blockSize = 16
+	}
+	blocksX, blocksY := (width+3)/4, (height+3)/4
+	if len(data) < blocksX*blocksY*blockSize {
+		return nil, fmt.Errorf("dxt data is too short: %d bytes for %dx%d", len(data), width, height)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	var alpha [16]uint8
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			block := data[(by*blocksX+bx)*blockSize:]
+			if dxt5 {
+				decodeDXT5Alpha(block[:8], &alpha)
+				block = block[8:]
+			}
+			colors := dxtColorPalette(block[:4], !dxt5)
+			indices := binary.LittleEndian.Uint32(block[4:8])
+			for i := 0; i < 16; i++ {
+				x, y := bx*4+i%4, by*4+i/4
+				if x >= width || y >= height {
+					continue
+				}
+				c := colors[indices>>(2*i)&3]
+				if dxt5 {
+					c.A = alpha[i]
+				}
+				img.SetNRGBA(x, height-1-y, c)
+			}
+		}
+	}
+	return img, nil
+}
+
+// dxtColorPalette 由两个 RGB565 端点计算块内的四种颜色，DXT1 中 c0 <= c1 时第四种颜色为透明
+func dxtColorPalette(endpoints []byte, dxt1 bool) [4]color.NRGBA {
+	c0 := binary.LittleEndian.Uint16(endpoints[0:2])
+	c1 := binary.LittleEndian.Uint16(endpoints[2:4])
+	var palette [4]color.NRGBA
+	palette[0] = rgb565(c0)
+	palette[1] = rgb565(c1)
+	mix := func(a, b uint8, wa, wb, d int) uint8 {
+		return uint8((int(a)*wa + int(b)*wb) / d)
+	}
+	p0, p1 := palette[0], palette[1]
+	if c0 > c1 || !dxt1 {
+		palette[2] = color.NRGBA{R: mix(p0.R, p1.R, 2, 1, 3), G: mix(p0.G, p1.G, 2, 1, 3), B: mix(p0.B, p1.B, 2, 1, 3), A: 255}
+		palette[3] = color.NRGBA{R: mix(p0.R, p1.R, 1, 2, 3), G: mix(p0.G, p1.G, 1, 2, 3), B: mix(p0.B, p1.B, 1, 2, 3), A: 255}
+	} else {
+		palette[2] = color.NRGBA{R: mix(p0.R, p1.R, 1, 1, 2), G: mix(p0.G, p1.G, 1, 1, 2), B: mix(p0.B, p1.B, 1, 1, 2), A: 255}
+		palette[3] = color.NRGBA{}
+	}
+	return palette
+}
+
+// rgb565 将 RGB565 颜色展开为 8 位每通道
+func rgb565(c uint16) color.NRGBA {
+	r, g, b := uint8(c>>11&0x1F), uint8(c>>5&0x3F), uint8(c&0x1F)
+	return color.NRGBA{R: r<<3 | r>>2, G: g<<2 | g>>4, B: b<<3 | b>>2, A: 255}
+}
+
+// decodeDXT5Alpha 解压 DXT5 块的透明度，两个端点加 16 个 3 位索引
+func decodeDXT5Alpha(block []byte, alpha *[16]uint8) {
+	a0, a1 := int(block[0]), int(block[1])
+	var palette [8]uint8
+	palette[0], palette[1] = uint8(a0), uint8(a1)
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = uint8(((7-i)*a0 + i*a1) / 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = uint8(((5-i)*a0 + i*a1) / 5)
+		}
+		palette[6], palette[7] = 0, 255
+	}
+	var bits uint64
+	for i := 0; i < 6; i++ {
+		bits |= uint64(block[2+i]) << (8 * i)
+	}
+	for i := range alpha {
+		alpha[i] = palette[bits>>(3*i)&7]
+	}
+}